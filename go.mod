@@ -8,6 +8,8 @@ require (
 	github.com/go-chi/chi/v5 v5.0.10
 	github.com/nats-io/jsm.go v0.1.0
 	github.com/nats-io/nats.go v1.30.0
+	github.com/nats-io/nkeys v0.4.5
+	golang.org/x/net v0.15.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -17,7 +19,6 @@ require (
 	github.com/godbus/dbus/v5 v5.0.4 // indirect
 	github.com/klauspost/compress v1.17.0 // indirect
 	github.com/kr/text v0.2.0 // indirect
-	github.com/nats-io/nkeys v0.4.5 // indirect
 	github.com/nats-io/nuid v1.0.1 // indirect
 	golang.org/x/crypto v0.13.0 // indirect
 	golang.org/x/sys v0.12.0 // indirect
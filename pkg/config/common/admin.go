@@ -0,0 +1,32 @@
+package common
+
+import (
+	"time"
+
+	"github.com/svalevka/go/pkg/config"
+)
+
+// Admin configures the administrative HTTP server of a service, used to change
+// log levels at runtime.
+type Admin struct {
+	// Listen optionally enables the admin server on a host:port, which
+	// should not be publicly reachable, such as localhost:9090.
+	Listen string `yaml:"listen" validate:"hostport"`
+
+	// Token authenticates requests to the admin server as a bearer token,
+	// and is required if the admin server is enabled.
+	Token config.Secret `yaml:"token" secret:"true"`
+
+	// LevelTTL is how long log levels changed at runtime last before the
+	// configured levels are restored.
+	LevelTTL time.Duration `yaml:"level_ttl" default:"15m" validate:"min=1s"`
+}
+
+// Validate checks a Token is configured if the admin server is enabled.
+func (a *Admin) Validate() error {
+	if a.Listen != "" && a.Token == "" {
+		return &config.ValidationError{Field: "token", Message: "is required if listen is set"}
+	}
+
+	return nil
+}
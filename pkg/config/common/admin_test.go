@@ -0,0 +1,32 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/svalevka/go/pkg/config"
+)
+
+func TestAdminValidation(t *testing.T) {
+	for _, tt := range []struct {
+		admin Admin
+		want  string
+	}{
+		{Admin{}, ""},
+		{Admin{Listen: "localhost:9090", Token: "secret"}, ""},
+		{Admin{Listen: "localhost:9090"}, "token: is required if listen is set"},
+		{Admin{Listen: "localhost"}, "listen:"},
+	} {
+		a := tt.admin
+
+		err := config.SetDefaults(&a)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = config.Validate(&a)
+		if tt.want == "" && err != nil || tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("Validate(%+v) = %v, want %q", tt.admin, err, tt.want)
+		}
+	}
+}
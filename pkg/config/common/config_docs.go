@@ -0,0 +1,82 @@
+// Code generated by docgen. DO NOT EDIT.
+
+package common
+
+import "github.com/svalevka/go/pkg/config"
+
+func init() {
+	config.RegisterDocs("github.com/svalevka/go/pkg/config/common", map[string]string{
+		"Admin":                        "Admin configures the administrative HTTP server of a service, used to change log levels at runtime.",
+		"Admin.LevelTTL":               "LevelTTL is how long log levels changed at runtime last before the configured levels are restored.",
+		"Admin.Listen":                 "Listen optionally enables the admin server on a host:port, which should not be publicly reachable, such as localhost:9090.",
+		"Admin.Token":                  "Token authenticates requests to the admin server as a bearer token, and is required if the admin server is enabled.",
+		"ClientTLS":                    "ClientTLS configures the TLS connections of clients, where the certificate files are reloaded when they change, so they can be renewed without a restart.",
+		"ClientTLS.CA":                 "CA is the path of a PEM bundle of certificate authorities trusted to verify servers, instead of those of the system.",
+		"ClientTLS.Cert":               "Cert is the path of a PEM client certificate presented to servers, which requires Key.",
+		"ClientTLS.Key":                "Key is the path of the PEM private key of Cert.",
+		"ClientTLS.ServerName":         "ServerName overrides the name the certificates of servers are verified against, which is otherwise the host connected to. It is required with CA to connect to servers by IP address, such as 10.0.0.5.",
+		"HTTPServer":                   "HTTPServer configures an HTTP server.",
+		"HTTPServer.Addr":              "Addr is the host:port the server accepts connections on.",
+		"HTTPServer.H2C":               "H2C serves HTTP/2 without TLS, such as behind a proxy terminating TLS, in addition to HTTP/1.1. It can't be combined with TLS, which serves HTTP/2 itself.",
+		"HTTPServer.IdleTimeout":       "IdleTimeout is how long connections are kept open between requests, or ReadTimeout if zero.",
+		"HTTPServer.MaxHeaderBytes":    "MaxHeaderBytes is the maximum size of the headers of a request, or 1MB if zero.",
+		"HTTPServer.ReadHeaderTimeout": "ReadHeaderTimeout is how long clients may take to send the headers of a request, or ReadTimeout if zero.",
+		"HTTPServer.ReadTimeout":       "ReadTimeout is how long clients may take to send a request, including its body, or unlimited if zero.",
+		"HTTPServer.ShutdownTimeout":   "ShutdownTimeout is how long requests in progress may take to complete when the server is stopped, after which their connections are closed, or zero to close them immediately.",
+		"HTTPServer.TLS":               "TLS optionally serves HTTPS, and can require client certificates.",
+		"HTTPServer.WriteTimeout":      "WriteTimeout is how long handling a request and writing its response may take, or unlimited if zero.",
+		"LogRedaction":                 "LogRedaction configures patterns of secrets redacted from records.",
+		"LogRedaction.Keys":            "Keys are regular expressions matching the keys of attributes whose values are redacted.",
+		"LogRedaction.Values":          "Values are regular expressions matching secrets within messages and attribute values, where only the first capture group is redacted if there is one, otherwise the whole match.",
+		"LogRotation":                  "LogRotation configures the rotation of log files, where rotated files have the time of rotation appended to their path.",
+		"LogRotation.MaxAge":           "MaxAge optionally rotates the log file after it has been written to for a duration, such as 24h.",
+		"LogRotation.MaxBackups":       "MaxBackups is the number of rotated log files that are kept.",
+		"LogRotation.MaxSize":          "MaxSize is the size in megabytes the log file is rotated before exceeding.",
+		"LogSampling":                  "LogSampling configures the sampling of repeated records, where the number of records dropped is logged as a warning every interval.",
+		"LogSampling.First":            "First is the number of repeated records written each interval, or 0 to disable sampling.",
+		"LogSampling.Interval":         "Interval is how long repeated records are counted for.",
+		"LogSampling.Thereafter":       "Thereafter writes every Thereafter-th repeated record beyond First each interval, or none if 0.",
+		"Logs":                         "Logs contains common logging configuration used by all services.",
+		"Logs.AddSource":               "AddSource adds the source file and line of the log statement to records.",
+		"Logs.Components":              "Components overrides the level of records logged by components, keyed by the value of their task or service attribute, such as Stats or ConfigReloader, where the level of a task overrides its service.",
+		"Logs.Debug":                   "Debug enabled debugging information in logs, otherwise discarded. It is equivalent to a level of debug, which it overrides.",
+		"Logs.Format":                  "Format is the format records are written in, one of json, text for human-readable lines, or logfmt.",
+		"Logs.Level":                   "Level is the minimum level of records written, one of debug, info, warn or error.",
+		"Logs.Output":                  "Output is where records are written, either stdout, stderr, journald for the systemd journal, where attributes are stored as journal fields regardless of the format, or the path of a log file.",
+		"Logs.Redact":                  "Redact configures the redaction of secrets from records, in addition to attributes with keys such as password or token, URLs with passwords and fields tagged secret, which are always redacted.",
+		"Logs.Rotate":                  "Rotate configures the rotation of the log file, if records are written to a file.",
+		"Logs.Sampling":                "Sampling optionally limits how many repeated records are written, being records with the same level, message and attributes.",
+		"NATS":                         "NATS contains configuration common to all NATS clients.",
+		"NATS.Credentials":             "Credentials optionally authenticates with the user JWT and NKey seed of a .creds file at this path, which is read on every connection.",
+		"NATS.DrainTimeout":            "DrainTimeout is how long draining subscriptions may take when the connection is drained.",
+		"NATS.MaxPingsOut":             "MaxPingsOut is the number of pings without a reply before the connection is considered stale and reconnected.",
+		"NATS.MaxReconnects":           "MaxReconnects is the number of attempts to reconnect before the connection is closed, or -1 to reconnect forever.",
+		"NATS.NKey":                    "NKey optionally authenticates with the NKey seed of the file at this path.",
+		"NATS.Password":                "Password optionally configures the password to authenticate with, no authentication will take place if empty. As a secret, it should be given as a reference such as ${file:/run/secrets/nats} or ${cred:nats}.",
+		"NATS.PingInterval":            "PingInterval is how often servers are pinged to detect stale connections, or zero to never ping them.",
+		"NATS.ReconnectWait":           "ReconnectWait is how long to wait before reconnecting to a server that was connected to, or zero to reconnect immediately.",
+		"NATS.Servers":                 "Servers is an array of at least one NATS server to connect to, depending on client implementation, more servers will be discovered.",
+		"NATS.TLS":                     "TLS optionally configures TLS, which is required if any setting is configured or servers have the tls:// scheme.",
+		"NATS.Timeout":                 "Timeout is how long connecting to a server may take.",
+		"NATS.Token":                   "Token optionally authenticates with a token, given as a reference like Password.",
+		"NATS.Username":                "Username optionally configures the username to authenticate as, no authentication will take place if empty.",
+		"ServerTLS":                    "ServerTLS configures the TLS of servers, where the certificate files are reloaded when they change, so they can be renewed without a restart.",
+		"ServerTLS.Cert":               "Cert is the path of the PEM certificate of the server, which enables TLS and requires Key.",
+		"ServerTLS.ClientCA":           "ClientCA is the path of a PEM bundle of certificate authorities, which requires clients to present a certificate they issued.",
+		"ServerTLS.Key":                "Key is the path of the PEM private key of Cert.",
+		"StatsD":                       "StatsD configures a client writing metrics to a StatsD server, such as the DataDog agent. Buffering and transport options are left to the client unless configured.",
+		"StatsD.Aggregation":           "Aggregation is how metrics are aggregated before they are sent, either none, basic for gauges, counts and sets, or extended to also include histograms and distributions.",
+		"StatsD.BufferFlushInterval":   "BufferFlushInterval is how often partially filled packets are sent.",
+		"StatsD.BufferPoolSize":        "BufferPoolSize is the number of packets buffered before metrics are dropped.",
+		"StatsD.Env":                   "Env is optionally added to every metric as the env tag, such as prod.",
+		"StatsD.Host":                  "Host is the address of the StatsD server, either a host:port for UDP, or a Unix domain socket such as unix:///var/run/datadog/dsd.socket, where unixgram:// or unixstream:// force the type of socket.",
+		"StatsD.HostTag":               "HostTag adds the hostname to every metric as the host tag, which is otherwise added by the DataDog agent.",
+		"StatsD.MaxBytesPerPayload":    "MaxBytesPerPayload is the maximum size of the packets sent.",
+		"StatsD.MaxMessagesPerPayload": "MaxMessagesPerPayload is the maximum number of metrics in each packet.",
+		"StatsD.Namespace":             "Namespace is optionally prefixed to the name of every metric, such as myservice.",
+		"StatsD.SenderQueueSize":       "SenderQueueSize is the number of packets queued to be sent.",
+		"StatsD.Tags":                  "Tags are added to every metric, such as team:infra, in addition to the revision of the service.",
+		"StatsD.Telemetry":             "Telemetry sends metrics about the client itself, such as the number of metrics dropped.",
+		"StatsD.WriteTimeout":          "WriteTimeout is how long writing to a Unix domain socket may take.",
+	})
+}
@@ -0,0 +1,90 @@
+package common
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"github.com/svalevka/go/pkg/config"
+)
+
+// HTTPServer configures an HTTP server.
+type HTTPServer struct {
+	// Addr is the host:port the server accepts connections on.
+	Addr string `yaml:"addr" validate:"required,hostport"`
+
+	// TLS optionally serves HTTPS, and can require client certificates.
+	TLS ServerTLS `yaml:"tls"`
+
+	// ReadHeaderTimeout is how long clients may take to send the headers of
+	// a request, or ReadTimeout if zero.
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" default:"10s" validate:"min=0s"`
+
+	// ReadTimeout is how long clients may take to send a request, including
+	// its body, or unlimited if zero.
+	ReadTimeout time.Duration `yaml:"read_timeout" default:"30s" validate:"min=0s"`
+
+	// WriteTimeout is how long handling a request and writing its response
+	// may take, or unlimited if zero.
+	WriteTimeout time.Duration `yaml:"write_timeout" default:"60s" validate:"min=0s"`
+
+	// IdleTimeout is how long connections are kept open between requests,
+	// or ReadTimeout if zero.
+	IdleTimeout time.Duration `yaml:"idle_timeout" default:"2m" validate:"min=0s"`
+
+	// MaxHeaderBytes is the maximum size of the headers of a request, or 1MB
+	// if zero.
+	MaxHeaderBytes int `yaml:"max_header_bytes" default:"65536" validate:"min=0"`
+
+	// ShutdownTimeout is how long requests in progress may take to complete
+	// when the server is stopped, after which their connections are closed,
+	// or zero to close them immediately.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" default:"30s" validate:"min=0s"`
+
+	// H2C serves HTTP/2 without TLS, such as behind a proxy terminating TLS,
+	// in addition to HTTP/1.1. It can't be combined with TLS, which serves
+	// HTTP/2 itself.
+	H2C bool `yaml:"h2c"`
+}
+
+// Validate checks H2C is not combined with TLS.
+func (h *HTTPServer) Validate() error {
+	if h.H2C && h.TLS.Enabled() {
+		return &config.ValidationError{Field: "h2c", Message: "can't be combined with tls"}
+	}
+
+	return nil
+}
+
+// Server returns an http.Server configured by HTTPServer serving handler,
+// which must be started with ListenAndServeTLS and empty paths if TLS is
+// enabled, as its certificates are loaded by its TLSConfig.
+func (h *HTTPServer) Server(handler http.Handler) (*http.Server, error) {
+	s := &http.Server{
+		Addr:              h.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: h.ReadHeaderTimeout,
+		ReadTimeout:       h.ReadTimeout,
+		WriteTimeout:      h.WriteTimeout,
+		IdleTimeout:       h.IdleTimeout,
+		MaxHeaderBytes:    h.MaxHeaderBytes,
+	}
+
+	if h.TLS.Enabled() {
+		tlsConfig, err := h.TLS.Config()
+		if err != nil {
+			return nil, fmt.Errorf("tls: %w", err)
+		}
+
+		s.TLSConfig = tlsConfig
+	} else if h.H2C {
+		s.Handler = h2c.NewHandler(handler, &http2.Server{
+			IdleTimeout: s.IdleTimeout,
+		})
+	}
+
+	return s, nil
+}
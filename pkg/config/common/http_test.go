@@ -0,0 +1,190 @@
+package common
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	stdlog "log"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+
+	"github.com/svalevka/go/pkg/config"
+)
+
+// serve serves requests with the server configured by h until the test ends,
+// responding with the protocol and client certificate of requests, and
+// returns the address it listens on.
+func serve(t *testing.T, h *HTTPServer) string {
+	t.Helper()
+
+	s, err := h.Server(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := ""
+		if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+			client = r.TLS.PeerCertificates[0].Subject.CommonName
+		}
+
+		fmt.Fprintf(w, "%s %s", r.Proto, client)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the failed handshakes of clients without certificates aren't logged.
+	s.ErrorLog = stdlog.New(io.Discard, "", 0)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		if s.TLSConfig != nil {
+			s.ServeTLS(ln, "", "")
+		} else {
+			s.Serve(ln)
+		}
+	}()
+
+	t.Cleanup(func() { s.Close() })
+
+	return ln.Addr().String()
+}
+
+// get returns the body of the response to a GET request to url with client.
+func get(client *http.Client, url string) (string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	return string(b), err
+}
+
+func TestHTTPServerServer(t *testing.T) {
+	h := &HTTPServer{Addr: "localhost:8080"}
+
+	err := config.SetDefaults(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := h.Server(http.NotFoundHandler())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Addr != "localhost:8080" || s.ReadHeaderTimeout != 10*time.Second || s.ReadTimeout != 30*time.Second ||
+		s.WriteTimeout != time.Minute || s.IdleTimeout != 2*time.Minute || s.MaxHeaderBytes != 65536 {
+		t.Errorf("server = %+v, want configured by HTTPServer", s)
+	}
+
+	if s.TLSConfig != nil {
+		t.Error("want TLS disabled")
+	}
+}
+
+func TestHTTPServerTLS(t *testing.T) {
+	ca := newTestCA(t)
+
+	cert, key := ca.issue(t, t.TempDir(), "localhost", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, t.TempDir(), "client", x509.ExtKeyUsageClientAuth)
+
+	addr := serve(t, &HTTPServer{TLS: ServerTLS{Cert: cert, Key: key, ClientCA: ca.file}})
+	url := "https://" + strings.Replace(addr, "127.0.0.1", "localhost", 1)
+
+	client := func(c *ClientTLS) *http.Client {
+		t.Helper()
+
+		cfg, err := c.Config()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+	}
+
+	body, err := get(client(&ClientTLS{CA: ca.file, Cert: clientCert, Key: clientKey}), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if body != "HTTP/1.1 client" {
+		t.Errorf("body = %q, want the client certificate verified", body)
+	}
+
+	// client certificates are required.
+	_, err = get(client(&ClientTLS{CA: ca.file}), url)
+	if err == nil {
+		t.Error("want an error without a client certificate")
+	}
+
+	other := newTestCA(t)
+	otherCert, otherKey := other.issue(t, t.TempDir(), "other", x509.ExtKeyUsageClientAuth)
+
+	_, err = get(client(&ClientTLS{CA: ca.file, Cert: otherCert, Key: otherKey}), url)
+	if err == nil {
+		t.Error("want an error for a client certificate of another authority")
+	}
+}
+
+func TestHTTPServerH2C(t *testing.T) {
+	addr := serve(t, &HTTPServer{H2C: true})
+
+	h2c := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+
+	for _, tt := range []struct {
+		client *http.Client
+		want   string
+	}{
+		{h2c, "HTTP/2.0 "},
+		{http.DefaultClient, "HTTP/1.1 "},
+	} {
+		body, err := get(tt.client, "http://"+addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if body != tt.want {
+			t.Errorf("body = %q, want %q", body, tt.want)
+		}
+	}
+}
+
+func TestHTTPServerValidation(t *testing.T) {
+	for _, tt := range []struct {
+		http HTTPServer
+		want string
+	}{
+		{HTTPServer{Addr: "localhost:8080", TLS: ServerTLS{Cert: "cert.pem", Key: "key.pem", ClientCA: "ca.pem"}}, ""},
+		{HTTPServer{}, "addr: is required"},
+		{HTTPServer{Addr: "localhost:8080", TLS: ServerTLS{Cert: "cert.pem"}}, "tls: cert and key must be configured together"},
+		{HTTPServer{Addr: "localhost:8080", TLS: ServerTLS{ClientCA: "ca.pem"}}, "tls.client_ca: requires cert and key to be configured"},
+		{HTTPServer{Addr: "localhost:8080", ReadTimeout: -1}, "read_timeout:"},
+		{HTTPServer{Addr: "localhost:8080", TLS: ServerTLS{Cert: "cert.pem", Key: "key.pem"}, H2C: true}, "h2c: can't be combined with tls"},
+	} {
+		h := tt.http
+
+		err := config.SetDefaults(&h)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = config.Validate(&h)
+		if tt.want == "" && err != nil || tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("Validate(%+v) = %v, want %q", tt.http, err, tt.want)
+		}
+	}
+}
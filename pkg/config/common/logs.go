@@ -1,24 +1,246 @@
 package common
 
 import (
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/svalevka/go/pkg/config"
+	"github.com/svalevka/go/pkg/log"
 )
 
+//go:generate go run github.com/svalevka/go/pkg/config/docgen
+
 // Logs contains common logging configuration used by all services.
 type Logs struct {
-	// Debug enabled debugging information in logs, otherwise discarded.
+	// Debug enabled debugging information in logs, otherwise discarded. It
+	// is equivalent to a level of debug, which it overrides.
 	Debug bool `yaml:"debug"`
+
+	// Level is the minimum level of records written, one of debug, info,
+	// warn or error.
+	Level LogLevel `yaml:"level" default:"info"`
+
+	// Components overrides the level of records logged by components, keyed
+	// by the value of their task or service attribute, such as Stats or
+	// ConfigReloader, where the level of a task overrides its service.
+	Components map[string]LogLevel `yaml:"components"`
+
+	// Format is the format records are written in, one of json, text for
+	// human-readable lines, or logfmt.
+	Format string `yaml:"format" default:"json" validate:"oneof=json text logfmt"`
+
+	// Output is where records are written, either stdout, stderr, journald
+	// for the systemd journal, where attributes are stored as journal fields
+	// regardless of the format, or the path of a log file.
+	Output string `yaml:"output" default:"stdout" validate:"required"`
+
+	// Rotate configures the rotation of the log file, if records are written
+	// to a file.
+	Rotate LogRotation `yaml:"rotate"`
+
+	// AddSource adds the source file and line of the log statement to
+	// records.
+	AddSource bool `yaml:"add_source"`
+
+	// Redact configures the redaction of secrets from records, in addition
+	// to attributes with keys such as password or token, URLs with passwords
+	// and fields tagged secret, which are always redacted.
+	Redact LogRedaction `yaml:"redact"`
+
+	// Sampling optionally limits how many repeated records are written,
+	// being records with the same level, message and attributes.
+	Sampling LogSampling `yaml:"sampling"`
+}
+
+// LogRotation configures the rotation of log files, where rotated files have
+// the time of rotation appended to their path.
+type LogRotation struct {
+	// MaxSize is the size in megabytes the log file is rotated before
+	// exceeding.
+	MaxSize int `yaml:"max_size" default:"100" validate:"min=0"`
+
+	// MaxAge optionally rotates the log file after it has been written to for
+	// a duration, such as 24h.
+	MaxAge time.Duration `yaml:"max_age" validate:"min=0s"`
+
+	// MaxBackups is the number of rotated log files that are kept.
+	MaxBackups int `yaml:"max_backups" default:"7" validate:"min=0"`
+}
+
+// LogRedaction configures patterns of secrets redacted from records.
+type LogRedaction struct {
+	// Keys are regular expressions matching the keys of attributes whose
+	// values are redacted.
+	Keys []string `yaml:"keys" validate:"regexp"`
+
+	// Values are regular expressions matching secrets within messages and
+	// attribute values, where only the first capture group is redacted if
+	// there is one, otherwise the whole match.
+	Values []string `yaml:"values" validate:"regexp"`
 }
 
-// GetLogger returns a slog.Logger configured by Logs.
+// LogSampling configures the sampling of repeated records, where the number
+// of records dropped is logged as a warning every interval.
+type LogSampling struct {
+	// First is the number of repeated records written each interval, or 0 to
+	// disable sampling.
+	First int `yaml:"first" validate:"min=0"`
+
+	// Thereafter writes every Thereafter-th repeated record beyond First
+	// each interval, or none if 0.
+	Thereafter int `yaml:"thereafter" validate:"min=0"`
+
+	// Interval is how long repeated records are counted for.
+	Interval time.Duration `yaml:"interval" default:"1s" validate:"min=1ms"`
+}
+
+// LogLevel is the name of a log level, one of debug, info, warn or error,
+// optionally with an offset such as debug-4, see slog.Level.
+type LogLevel string
+
+// Validate checks the LogLevel can be parsed.
+func (l LogLevel) Validate() error {
+	_, err := l.parse()
+	return err
+}
+
+// JSONSchema describes the named levels, though offsets are also accepted.
+func (l LogLevel) JSONSchema() *config.Schema {
+	return &config.Schema{
+		Type:    "string",
+		Pattern: `^(debug|info|warn|error|DEBUG|INFO|WARN|ERROR)([+-][0-9]+)?$`,
+	}
+}
+
+// parse returns the slog.Level of the LogLevel.
+func (l LogLevel) parse() (slog.Level, error) {
+	var level slog.Level
+
+	err := level.UnmarshalText([]byte(l))
+	if err != nil {
+		return 0, fmt.Errorf("invalid log level %q, expected one of debug, info, warn or error", string(l))
+	}
+
+	return level, nil
+}
+
+// GetLogger returns a slog.Logger configured by Logs, whose levels can be
+// changed at runtime, see log.LevelsOf. If the log file or the journal can't
+// be opened, records are written to stderr instead, beginning with the error.
+// Secrets are redacted from records, and repeated records are sampled if
+// configured.
 func (l *Logs) GetLogger() *slog.Logger {
-	level := slog.LevelInfo
+	levels := &log.Levels{}
+	l.ApplyLevels(levels)
+
+	opts := &slog.HandlerOptions{
+		AddSource: l.AddSource,
+		Level:     log.AllLevels,
+	}
+
+	h, openErr := l.handler(opts)
+	if openErr != nil {
+		h = l.formatHandler(os.Stderr, opts)
+	}
+
+	h = log.NewRedactHandler(h,
+		append(slices.Clip(log.DefaultRedactKeys), compilePatterns(l.Redact.Keys)...),
+		append(slices.Clip(log.DefaultRedactValues), compilePatterns(l.Redact.Values)...))
+
+	if l.Sampling.First > 0 {
+		h = log.NewSampleHandler(h, log.SampleOptions{
+			First:      l.Sampling.First,
+			Thereafter: l.Sampling.Thereafter,
+			Interval:   l.Sampling.Interval,
+		})
+	}
+
+	logger := slog.New(log.NewLevelHandler(h, levels))
+
+	if openErr != nil {
+		logger.Error("could not open log output, logging to stderr", slog.String("error", openErr.Error()))
+	}
+
+	return logger
+}
+
+// ApplyLevels sets the default and component levels of levels to those
+// configured by Logs, such as when the configuration is reloaded.
+func (l *Logs) ApplyLevels(levels *log.Levels) {
+	level, _ := l.Level.parse()
 	if l.Debug {
 		level = slog.LevelDebug
 	}
 
-	return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: level,
-	}))
+	levels.Default.Set(level)
+
+	components := map[string]slog.Level{}
+	for component, cl := range l.Components {
+		level, err := cl.parse()
+		if err == nil {
+			components[component] = level
+		}
+	}
+
+	levels.Replace(components)
+}
+
+// handler returns the handler writing records to the configured output.
+func (l *Logs) handler(opts *slog.HandlerOptions) (slog.Handler, error) {
+	switch strings.ToLower(l.Output) {
+	case "", "stdout":
+		return l.formatHandler(os.Stdout, opts), nil
+	case "stderr":
+		return l.formatHandler(os.Stderr, opts), nil
+	case "journald":
+		return log.NewJournalHandler("", opts)
+	}
+
+	f := &log.File{
+		Path:       l.Output,
+		MaxSize:    int64(l.Rotate.MaxSize) * 1024 * 1024,
+		MaxAge:     l.Rotate.MaxAge,
+		MaxBackups: l.Rotate.MaxBackups,
+	}
+
+	err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	return l.formatHandler(f, opts), nil
+}
+
+// compilePatterns returns the compiled regular expressions patterns, skipping
+// those that are invalid, which are rejected by validation.
+func compilePatterns(patterns []string) []*regexp.Regexp {
+	compiled := []*regexp.Regexp{}
+
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err == nil {
+			compiled = append(compiled, re)
+		}
+	}
+
+	return compiled
+}
+
+// formatHandler returns the handler writing records to w in the configured
+// format.
+func (l *Logs) formatHandler(w io.Writer, opts *slog.HandlerOptions) slog.Handler {
+	switch l.Format {
+	case "text":
+		return log.NewTextHandler(w, opts)
+	case "logfmt":
+		return slog.NewTextHandler(w, opts)
+	}
+
+	return slog.NewJSONHandler(w, opts)
 }
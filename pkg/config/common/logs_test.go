@@ -0,0 +1,158 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/svalevka/go/pkg/config"
+)
+
+// logs returns Logs writing to a file with defaults applied, configured by
+// configure, and the path of the file.
+func logs(t *testing.T, configure func(*Logs)) (*Logs, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "service.log")
+
+	l := &Logs{Output: path}
+	configure(l)
+
+	err := config.SetDefaults(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = config.Validate(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return l, path
+}
+
+// records returns the JSON records written to the file at path.
+func records(t *testing.T, path string) []map[string]any {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records := []map[string]any{}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		if line == "" {
+			continue
+		}
+
+		record := map[string]any{}
+
+		err := json.Unmarshal([]byte(line), &record)
+		if err != nil {
+			t.Fatalf("invalid record %q: %v", line, err)
+		}
+
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func TestLogsLevels(t *testing.T) {
+	l, path := logs(t, func(l *Logs) {
+		l.Level = "warn"
+		l.Components = map[string]LogLevel{"foo": "info", "Stats": "debug-4"}
+	})
+
+	logger := l.GetLogger()
+
+	logger.Info("discarded")
+	logger.Warn("warning")
+	logger.With("service", "foo").Info("service info")
+	logger.With("service", "foo", "task", "Stats").Log(context.Background(), -8, "task trace")
+
+	got := []string{}
+	for _, r := range records(t, path) {
+		got = append(got, r["msg"].(string))
+	}
+
+	if strings.Join(got, ",") != "warning,service info,task trace" {
+		t.Errorf("records = %v, want filtered by component", got)
+	}
+}
+
+func TestLogsDebug(t *testing.T) {
+	l, path := logs(t, func(l *Logs) {
+		l.Level = "error"
+		l.Debug = true
+	})
+
+	l.GetLogger().Debug("debug")
+
+	if r := records(t, path); len(r) != 1 {
+		t.Errorf("records = %v, want debug enabled", r)
+	}
+}
+
+func TestLogsFormats(t *testing.T) {
+	for format, want := range map[string]string{
+		"text":   "INFO  hello a=1\n",
+		"logfmt": "level=INFO msg=hello a=1\n",
+	} {
+		l, path := logs(t, func(l *Logs) { l.Format = format })
+
+		l.GetLogger().Info("hello", "a", 1)
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.HasSuffix(string(b), want) {
+			t.Errorf("%s = %q, want %q", format, b, want)
+		}
+	}
+}
+
+func TestLogsRedacts(t *testing.T) {
+	l, path := logs(t, func(l *Logs) {
+		l.Redact.Keys = []string{"^session$"}
+		l.Redact.Values = []string{"sk-[a-z0-9]+"}
+	})
+
+	l.GetLogger().Info("key sk-abc123", "session", "abc", "password", "hunter2")
+
+	r := records(t, path)[0]
+	if r["msg"] != "key "+config.Redacted || r["session"] != config.Redacted || r["password"] != config.Redacted {
+		t.Errorf("record = %v, want secrets redacted", r)
+	}
+}
+
+func TestLogsValidation(t *testing.T) {
+	l := &Logs{Level: "verbose", Format: "xml", Redact: LogRedaction{Keys: []string{"("}}}
+
+	err := config.SetDefaults(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = config.Validate(l)
+	if err == nil {
+		t.Fatal("want an error")
+	}
+
+	for _, want := range []string{
+		`level: invalid log level "verbose"`,
+		"format: must be one of json, text, logfmt",
+		"redact.keys[0]: must be a valid regular expression",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("err = %v, want %q", err, want)
+		}
+	}
+}
@@ -1,49 +1,283 @@
 package common
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
 	"github.com/nats-io/nats.go"
+
+	"github.com/svalevka/go/pkg/config"
+	"github.com/svalevka/go/pkg/log"
 )
 
 // NATS contains configuration common to all NATS clients.
 type NATS struct {
 	// Servers is an array of at least one NATS server to connect to, depending
 	// on client implementation, more servers will be discovered.
-	Servers []string `yaml:"servers"`
+	Servers []string `yaml:"servers" example:"[nats://localhost:4222]" validate:"required"`
 
 	// Username optionally configures the username to authenticate as, no
 	// authentication will take place if empty.
 	Username string `yaml:"username"`
 
 	// Password optionally configures the password to authenticate with, no
-	// authentication will take place if empty.
-	Password string `yaml:"password"`
+	// authentication will take place if empty. As a secret, it should be given
+	// as a reference such as ${file:/run/secrets/nats} or ${cred:nats}.
+	Password config.Secret `yaml:"password" secret:"true"`
+
+	// Credentials optionally authenticates with the user JWT and NKey seed of
+	// a .creds file at this path, which is read on every connection.
+	Credentials string `yaml:"credentials"`
+
+	// NKey optionally authenticates with the NKey seed of the file at this
+	// path.
+	NKey string `yaml:"nkey"`
+
+	// Token optionally authenticates with a token, given as a reference like
+	// Password.
+	Token config.Secret `yaml:"token" secret:"true"`
+
+	// TLS optionally configures TLS, which is required if any setting is
+	// configured or servers have the tls:// scheme.
+	TLS ClientTLS `yaml:"tls"`
+
+	// ReconnectWait is how long to wait before reconnecting to a server that
+	// was connected to, or zero to reconnect immediately.
+	ReconnectWait time.Duration `yaml:"reconnect_wait" default:"2s" validate:"min=0s"`
+
+	// MaxReconnects is the number of attempts to reconnect before the
+	// connection is closed, or -1 to reconnect forever.
+	MaxReconnects int `yaml:"max_reconnects" default:"60" validate:"min=-1"`
+
+	// PingInterval is how often servers are pinged to detect stale
+	// connections, or zero to never ping them.
+	PingInterval time.Duration `yaml:"ping_interval" default:"2m" validate:"min=0s"`
+
+	// MaxPingsOut is the number of pings without a reply before the
+	// connection is considered stale and reconnected.
+	MaxPingsOut int `yaml:"max_pings_out" default:"2" validate:"min=0"`
+
+	// Timeout is how long connecting to a server may take.
+	Timeout time.Duration `yaml:"timeout" default:"2s" validate:"min=1ms"`
+
+	// DrainTimeout is how long draining subscriptions may take when the
+	// connection is drained.
+	DrainTimeout time.Duration `yaml:"drain_timeout" default:"30s" validate:"min=0s"`
+}
+
+// Kinds of NATSEvent.
+const (
+	NATSDisconnected = "disconnected"
+	NATSReconnected  = "reconnected"
+	NATSClosed       = "closed"
+	NATSError        = "error"
+	NATSDiscovered   = "discovered_servers"
+)
+
+// NATSEvent describes a change in the state of a NATS connection, or an
+// asynchronous error, given to the handlers of NATS.Connect.
+type NATSEvent struct {
+	// Kind is one of NATSDisconnected, NATSReconnected, NATSClosed, NATSError
+	// or NATSDiscovered.
+	Kind string
+
+	// Conn is the connection the event occurred on.
+	Conn *nats.Conn
+
+	// Subscription is the subscription an error occurred on, such as a slow
+	// consumer, if any.
+	Subscription *nats.Subscription
+
+	// Err is the error of the event, if any.
+	Err error
+}
+
+// Validate checks the servers are valid and at most one authentication method
+// is configured.
+func (n *NATS) Validate() error {
+	errs := config.ValidationErrors{}
+
+	for i, server := range n.Servers {
+		msg := validateNATSServer(server)
+		if msg != "" {
+			errs = append(errs, (&config.ValidationError{Message: msg}).WrapIdx("servers", i))
+		}
+	}
+
+	methods := []string{}
+
+	if n.Username != "" || n.Password != "" {
+		methods = append(methods, "username")
+	}
+
+	if n.Credentials != "" {
+		methods = append(methods, "credentials")
+	}
+
+	if n.NKey != "" {
+		methods = append(methods, "nkey")
+	}
+
+	if n.Token != "" {
+		methods = append(methods, "token")
+	}
+
+	if len(methods) > 1 {
+		errs = append(errs, &config.ValidationError{
+			Message: "only one authentication method may be configured, got " + strings.Join(methods, ", "),
+		})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// validateNATSServer returns why a server is invalid, or an empty string if it
+// is valid, being either a URL or a host:port address.
+func validateNATSServer(server string) string {
+	if !strings.Contains(server, "://") {
+		_, _, err := net.SplitHostPort(server)
+		if err != nil {
+			return fmt.Sprintf("must be a URL or host:port address, got %q", server)
+		}
+
+		return ""
+	}
+
+	u, err := url.Parse(server)
+	if err != nil || u.Host == "" {
+		return fmt.Sprintf("must be a URL or host:port address, got %q", server)
+	}
+
+	switch u.Scheme {
+	case "nats", "tls", "ws", "wss":
+		return ""
+	}
+
+	return fmt.Sprintf("must have the scheme nats, tls, ws or wss, got %q", u.Scheme)
 }
 
 // Connect returns a NATS client configured by NATS, where clientName is used
-// to identify this connection to the NATS server for debugging.
-func (n *NATS) Connect(clientName string) (*nats.Conn, error) {
-	conn, err := nats.Options{
+// to identify this connection to the NATS server for debugging. Disconnects,
+// reconnects, asynchronous errors such as slow consumers, and other events are
+// logged to log.FromContext(ctx), and given to each of handlers, such as to
+// record metrics.
+func (n *NATS) Connect(ctx context.Context, clientName string, handlers ...func(*NATSEvent)) (*nats.Conn, error) {
+	opts := nats.Options{
 		AllowReconnect: true,
 		Servers:        n.Servers,
 		User:           n.Username,
-		Password:       n.Password,
+		Password:       n.Password.Value(),
+		Token:          n.Token.Value(),
 		Name:           clientName,
 
-		MaxReconnect:       nats.DefaultMaxReconnect,
-		ReconnectWait:      nats.DefaultReconnectWait,
+		MaxReconnect:       n.MaxReconnects,
+		ReconnectWait:      n.ReconnectWait,
 		ReconnectJitter:    nats.DefaultReconnectJitter,
 		ReconnectJitterTLS: nats.DefaultReconnectJitterTLS,
-		Timeout:            nats.DefaultTimeout,
-		PingInterval:       nats.DefaultPingInterval,
-		MaxPingsOut:        nats.DefaultMaxPingOut,
+		Timeout:            n.Timeout,
+		PingInterval:       n.PingInterval,
+		MaxPingsOut:        n.MaxPingsOut,
 		SubChanLen:         nats.DefaultMaxChanLen,
 		ReconnectBufSize:   nats.DefaultReconnectBufSize,
-		DrainTimeout:       nats.DefaultDrainTimeout,
+		DrainTimeout:       n.DrainTimeout,
 		FlusherTimeout:     nats.DefaultFlusherTimeout,
-	}.Connect()
+	}
+
+	n.handleEvents(&opts, log.FromContext(ctx), handlers)
+
+	if n.TLS.Enabled() {
+		tlsConfig, err := n.TLS.Config()
+		if err != nil {
+			return nil, fmt.Errorf("tls: %w", err)
+		}
+
+		opts.Secure = true
+		opts.TLSConfig = tlsConfig
+	}
+
+	if n.Credentials != "" {
+		err := nats.UserCredentials(n.Credentials)(&opts)
+		if err != nil {
+			return nil, fmt.Errorf("credentials: %w", err)
+		}
+	}
+
+	if n.NKey != "" {
+		nkey, err := nats.NkeyOptionFromSeed(n.NKey)
+		if err != nil {
+			return nil, fmt.Errorf("nkey: %w", err)
+		}
+
+		err = nkey(&opts)
+		if err != nil {
+			return nil, fmt.Errorf("nkey: %w", err)
+		}
+	}
+
+	conn, err := opts.Connect()
 	if err != nil {
 		return nil, err
 	}
 
 	return conn, nil
 }
+
+// handleEvents sets the callbacks of opts to log events of the connection to
+// logger, and pass them to handlers.
+func (n *NATS) handleEvents(opts *nats.Options, logger *slog.Logger, handlers []func(*NATSEvent)) {
+	emit := func(event *NATSEvent) {
+		for _, h := range handlers {
+			h(event)
+		}
+	}
+
+	opts.DisconnectedErrCB = func(c *nats.Conn, err error) {
+		if err != nil {
+			logger.Warn("nats disconnected", slog.String("error", err.Error()))
+		} else {
+			logger.Info("nats disconnected")
+		}
+
+		emit(&NATSEvent{Kind: NATSDisconnected, Conn: c, Err: err})
+	}
+
+	opts.ReconnectedCB = func(c *nats.Conn) {
+		logger.Info("nats reconnected", slog.String("server", c.ConnectedUrlRedacted()))
+		emit(&NATSEvent{Kind: NATSReconnected, Conn: c})
+	}
+
+	opts.ClosedCB = func(c *nats.Conn) {
+		err := c.LastError()
+		if err != nil {
+			logger.Warn("nats connection closed", slog.String("error", err.Error()))
+		} else {
+			logger.Info("nats connection closed")
+		}
+
+		emit(&NATSEvent{Kind: NATSClosed, Conn: c, Err: err})
+	}
+
+	opts.AsyncErrorCB = func(c *nats.Conn, sub *nats.Subscription, err error) {
+		attrs := []any{slog.String("error", err.Error())}
+		if sub != nil {
+			attrs = append(attrs, slog.String("subject", sub.Subject))
+		}
+
+		logger.Error("nats error", attrs...)
+		emit(&NATSEvent{Kind: NATSError, Conn: c, Subscription: sub, Err: err})
+	}
+
+	opts.DiscoveredServersCB = func(c *nats.Conn) {
+		logger.Info("nats servers discovered", slog.Any("servers", c.DiscoveredServers()))
+		emit(&NATSEvent{Kind: NATSDiscovered, Conn: c})
+	}
+}
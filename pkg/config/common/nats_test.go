@@ -0,0 +1,247 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/svalevka/go/pkg/config"
+	"github.com/svalevka/go/pkg/log"
+)
+
+// testNKeySeed is the seed of the user NKey testNKey.
+const (
+	testNKeySeed = "SUALJ2CFEYK356U3DI5EHZHISL62AE645AJMAEKYOWQUAWUYWMU5EUNZIM"
+	testNKey     = "UD5M4PLJEJKYPGVOPSQ7SN7CTASEQPJEYU5P6HHM776WRGQ7ZOUJNTFO"
+)
+
+// quietContext returns a context discarding the logs of connections.
+func quietContext() context.Context {
+	return log.NewContext(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestNATSValidation(t *testing.T) {
+	for _, tt := range []struct {
+		nats NATS
+		want string
+	}{
+		{NATS{Servers: []string{"nats://a:4222", "tls://b:4222", "c:4222"}, Token: "t"}, ""},
+		{NATS{}, "servers: is required"},
+		{NATS{Servers: []string{"localhost"}}, `servers[0]: must be a URL or host:port address, got "localhost"`},
+		{NATS{Servers: []string{"http://a:4222"}}, `servers[0]: must have the scheme nats, tls, ws or wss, got "http"`},
+		{NATS{Servers: []string{"a:4222"}, Username: "a", NKey: "a.nk", Token: "t"}, "only one authentication method may be configured, got username, nkey, token"},
+		{NATS{Servers: []string{"a:4222"}, TLS: ClientTLS{Key: "key.pem"}}, "tls: cert and key must be configured together"},
+		{NATS{Servers: []string{"a:4222"}, MaxReconnects: -2}, "max_reconnects:"},
+		{NATS{Servers: []string{"a:4222"}, Timeout: -1}, "timeout:"},
+	} {
+		n := tt.nats
+
+		err := config.SetDefaults(&n)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = config.Validate(&n)
+		if tt.want == "" && err != nil || tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("Validate(%+v) = %v, want %q", tt.nats, err, tt.want)
+		}
+	}
+}
+
+func TestNATSConnectAuth(t *testing.T) {
+	stub := newNATSStub(t)
+	dir := t.TempDir()
+
+	seed := filepath.Join(dir, "user.nk")
+	creds := filepath.Join(dir, "user.creds")
+
+	err := os.WriteFile(seed, []byte(testNKeySeed+"\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(creds, []byte(`-----BEGIN NATS USER JWT-----
+eyJ0eXAiOiJKV1QifQ.e30.c2ln
+------END NATS USER JWT------
+
+-----BEGIN USER NKEY SEED-----
+`+testNKeySeed+`
+------END USER NKEY SEED------
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		configure func(*NATS)
+		want      map[string]string
+		signed    bool
+	}{
+		{func(n *NATS) { n.Username, n.Password = "user", "pass" }, map[string]string{"user": "user", "pass": "pass"}, false},
+		{func(n *NATS) { n.Token = "token" }, map[string]string{"auth_token": "token"}, false},
+		{func(n *NATS) { n.NKey = seed }, map[string]string{"nkey": testNKey}, true},
+		{func(n *NATS) { n.Credentials = creds }, map[string]string{"jwt": "eyJ0eXAiOiJKV1QifQ.e30.c2ln"}, true},
+	} {
+		n := stub.config()
+		tt.configure(n)
+
+		conn, err := n.Connect(quietContext(), "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		conn.Close()
+
+		connect := map[string]any{}
+
+		err = json.Unmarshal([]byte(stub.LastConnect()), &connect)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for k, v := range tt.want {
+			if connect[k] != v {
+				t.Errorf("CONNECT %s = %v, want %s", k, connect[k], v)
+			}
+		}
+
+		// keys sign the nonce of the server.
+		if signed := connect["sig"] != nil; signed != tt.signed {
+			t.Errorf("CONNECT = %v, signed = %v, want %v", connect, signed, tt.signed)
+		}
+	}
+}
+
+func TestNATSConnectErrors(t *testing.T) {
+	stub := newNATSStub(t)
+	dir := t.TempDir()
+
+	for _, tt := range []struct {
+		configure func(*NATS)
+		want      string
+	}{
+		{func(n *NATS) { n.Credentials = filepath.Join(dir, "missing.creds") }, "credentials:"},
+		{func(n *NATS) { n.NKey = filepath.Join(dir, "missing.nk") }, "nkey:"},
+		{func(n *NATS) { n.TLS.CA = filepath.Join(dir, "missing.pem") }, "tls: load certificate authorities"},
+	} {
+		n := stub.config()
+		tt.configure(n)
+
+		conn, err := n.Connect(quietContext(), "test")
+		if err == nil {
+			conn.Close()
+		}
+
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("err = %v, want %q", err, tt.want)
+		}
+	}
+}
+
+func TestNATSConnectOptions(t *testing.T) {
+	stub := newNATSStub(t)
+
+	n := &NATS{Servers: stub.config().Servers, MaxReconnects: -1, PingInterval: time.Minute}
+
+	err := config.SetDefaults(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := n.Connect(quietContext(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	opts := conn.Opts
+	if opts.MaxReconnect != -1 || opts.PingInterval != time.Minute || opts.ReconnectWait != 2*time.Second ||
+		opts.MaxPingsOut != 2 || opts.Timeout != 2*time.Second || opts.DrainTimeout != 30*time.Second {
+		t.Errorf("options = %+v, want configured by NATS", opts)
+	}
+}
+
+func TestNATSConnectEvents(t *testing.T) {
+	stub := newNATSStub(t)
+	buf := &syncBuffer{}
+
+	n := stub.config()
+	n.MaxReconnects = 1
+	n.ReconnectWait = 10 * time.Millisecond
+
+	events := make(chan string, 10)
+
+	ctx := log.With(log.NewContext(context.Background(), slog.New(slog.NewTextHandler(buf, nil))), slog.String("task", "Events"))
+
+	conn, err := n.Connect(ctx, "test", func(e *NATSEvent) {
+		events <- e.Kind
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stub.Disconnect()
+	waitEvents(t, events, NATSDisconnected, NATSReconnected)
+
+	// closing disconnects first.
+	conn.Close()
+	waitEvents(t, events, NATSDisconnected, NATSClosed)
+
+	logs := buf.String()
+	for _, want := range []string{
+		"msg=\"nats disconnected\" task=Events",
+		"msg=\"nats reconnected\" task=Events server=nats://",
+		"msg=\"nats connection closed\" task=Events",
+	} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("logs = %s, want %s", logs, want)
+		}
+	}
+}
+
+// waitEvents waits for events of the kinds in order.
+func waitEvents(t *testing.T, events chan string, kinds ...string) {
+	t.Helper()
+
+	for _, want := range kinds {
+		select {
+		case kind := <-events:
+			if kind != want {
+				t.Fatalf("event = %s, want %s", kind, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no %s event", want)
+		}
+	}
+}
+
+func TestNATSAsyncError(t *testing.T) {
+	buf := &bytes.Buffer{}
+	opts := &nats.Options{}
+
+	var event *NATSEvent
+
+	(&NATS{}).handleEvents(opts, slog.New(slog.NewTextHandler(buf, nil)), []func(*NATSEvent){
+		func(e *NATSEvent) { event = e },
+	})
+
+	sub := &nats.Subscription{Subject: "events.>"}
+	opts.AsyncErrorCB(nil, sub, nats.ErrSlowConsumer)
+
+	if event == nil || event.Kind != NATSError || event.Subscription != sub || event.Err != nats.ErrSlowConsumer {
+		t.Errorf("event = %+v, want the slow consumer error", event)
+	}
+
+	if !strings.Contains(buf.String(), `level=ERROR msg="nats error" error="nats: slow consumer, messages dropped" subject=events.>`) {
+		t.Errorf("logs = %s, want the error logged with the subject", buf)
+	}
+}
@@ -0,0 +1,270 @@
+package common
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/svalevka/go/pkg/log"
+)
+
+// NATSPool shares NATS connections between the tasks of a service, where tasks
+// configured with identical NATS settings use the same connection. Connections
+// are counted by reference, and drained once no task uses them.
+//
+// NATSPool is also a Task, which drains every connection when it is stopped
+// and waits for them to close, so the service doesn't exit while messages are
+// still being processed. Tasks using the pool should depend on it, see
+// tasks.DependentTask, so they stop before their connections are drained.
+type NATSPool struct {
+	// ClientName identifies the connections to the NATS servers.
+	ClientName string
+
+	// Logger is where the events of connections are logged, or slog.Default
+	// if nil.
+	Logger *slog.Logger
+
+	// OnEvent is optionally given the events of every connection, such as to
+	// record metrics, see NATS.Connect.
+	OnEvent func(*NATSEvent)
+
+	mu    sync.Mutex
+	conns map[string]*pooledConn
+}
+
+// pooledConn is a connection of a NATSPool and the number of its users.
+type pooledConn struct {
+	conn        *nats.Conn
+	cfg         *NATS
+	fingerprint string
+	refs        int
+
+	// closed is closed once the connection is closed.
+	closed chan struct{}
+}
+
+// NATSConnStats describes a connection of a NATSPool.
+type NATSConnStats struct {
+	// Fingerprint identifies the settings of the connection, without
+	// revealing them.
+	Fingerprint string
+
+	// Servers are the configured servers of the connection.
+	Servers []string
+
+	// Refs is the number of users of the connection.
+	Refs int
+
+	// Status is the status of the connection, such as CONNECTED or
+	// RECONNECTING.
+	Status string
+
+	// Statistics are the messages and bytes sent and received, and the
+	// number of reconnects.
+	nats.Statistics
+}
+
+// Get returns a connection configured by n, connecting if no connection with
+// identical settings is in use. Each connection returned must be given to
+// Release once it is no longer used.
+func (p *NATSPool) Get(ctx context.Context, n *NATS) (*nats.Conn, error) {
+	fingerprint := n.fingerprint()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conns == nil {
+		p.conns = map[string]*pooledConn{}
+	}
+
+	if pc, ok := p.conns[fingerprint]; ok && !pc.conn.IsClosed() {
+		pc.refs++
+		return pc.conn, nil
+	}
+
+	logger := p.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	// connections outlive the task that first gets them, so their events
+	// are logged without the attributes of its context.
+	ctx = log.With(log.NewContext(context.Background(), logger), slog.String("nats_connection", fingerprint))
+
+	pc := &pooledConn{cfg: n, fingerprint: fingerprint, refs: 1, closed: make(chan struct{})}
+
+	handlers := []func(*NATSEvent){
+		func(e *NATSEvent) {
+			if e.Kind == NATSClosed {
+				close(pc.closed)
+			}
+		},
+	}
+
+	if p.OnEvent != nil {
+		handlers = append(handlers, p.OnEvent)
+	}
+
+	conn, err := n.Connect(ctx, p.ClientName, handlers...)
+	if err != nil {
+		return nil, err
+	}
+
+	pc.conn = conn
+	p.conns[fingerprint] = pc
+
+	return conn, nil
+}
+
+// Closed returns a channel which is closed once a connection returned by Get
+// is closed, such as after failing to reconnect MaxReconnects times, so tasks
+// using it can fail rather than wait for messages that never arrive.
+func (p *NATSPool) Closed(conn *nats.Conn) <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, pc := range p.conns {
+		if pc.conn == conn {
+			return pc.closed
+		}
+	}
+
+	// the connection was released, or replaced once it closed.
+	closed := make(chan struct{})
+	close(closed)
+
+	return closed
+}
+
+// Release gives back a connection returned by Get, draining it if it is no
+// longer used by any task.
+func (p *NATSPool) Release(conn *nats.Conn) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for fingerprint, pc := range p.conns {
+		if pc.conn != conn {
+			continue
+		}
+
+		pc.refs--
+		if pc.refs > 0 {
+			return nil
+		}
+
+		delete(p.conns, fingerprint)
+
+		return drain(conn)
+	}
+
+	// the connection was already drained by Close.
+	return nil
+}
+
+// Stats returns a description of each connection in use, ordered by their
+// servers and fingerprint.
+func (p *NATSPool) Stats() []*NATSConnStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := make([]*NATSConnStats, 0, len(p.conns))
+
+	for _, pc := range p.conns {
+		stats = append(stats, &NATSConnStats{
+			Fingerprint: pc.fingerprint,
+			Servers:     pc.cfg.Servers,
+			Refs:        pc.refs,
+			Status:      pc.conn.Status().String(),
+			Statistics:  pc.conn.Stats(),
+		})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		si, sj := fmt.Sprint(stats[i].Servers), fmt.Sprint(stats[j].Servers)
+		if si != sj {
+			return si < sj
+		}
+
+		return stats[i].Fingerprint < stats[j].Fingerprint
+	})
+
+	return stats
+}
+
+// Close drains every connection, regardless of whether they are in use, and
+// waits until they are closed, or their drain timeout has passed.
+func (p *NATSPool) Close() error {
+	p.mu.Lock()
+	conns := p.conns
+	p.conns = nil
+	p.mu.Unlock()
+
+	errs := []error{}
+	deadline := time.Now()
+
+	for _, pc := range conns {
+		err := drain(pc.conn)
+		if err != nil {
+			errs = append(errs, err)
+		}
+
+		// connections failing to drain in time are closed by the client,
+		// so are waited for a moment longer.
+		timeout := pc.cfg.DrainTimeout + time.Second
+		if d := time.Now().Add(timeout); d.After(deadline) {
+			deadline = d
+		}
+	}
+
+	for _, pc := range conns {
+		for !pc.conn.IsClosed() && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (p *NATSPool) TaskName() string {
+	return "NATSPool"
+}
+
+func (p *NATSPool) RunTask(ctx context.Context) error {
+	<-ctx.Done()
+
+	err := p.Close()
+	if err != nil {
+		return fmt.Errorf("drain: %w", err)
+	}
+
+	return ctx.Err()
+}
+
+// drain drains the subscriptions of a connection then closes it, unless it is
+// already draining or closed.
+func drain(conn *nats.Conn) error {
+	err := conn.Drain()
+	if errors.Is(err, nats.ErrConnectionClosed) || errors.Is(err, nats.ErrConnectionDraining) {
+		return nil
+	}
+
+	return err
+}
+
+// fingerprint returns a hash of the settings of n, identifying connections
+// with identical settings without revealing their secrets.
+func (n *NATS) fingerprint() string {
+	// secrets are redacted by %#v, so are hashed separately.
+	h := sha256.New()
+	fmt.Fprintf(h, "%#v\x00%s\x00%s", *n, n.Password.Value(), n.Token.Value())
+
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
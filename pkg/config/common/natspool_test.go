@@ -0,0 +1,240 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/svalevka/go/pkg/config/common/natstest"
+	"github.com/svalevka/go/pkg/log"
+)
+
+// natsStub is a NATS server for tests of the clients configured by NATS.
+type natsStub struct {
+	*natstest.Server
+}
+
+func newNATSStub(t *testing.T) *natsStub {
+	t.Helper()
+
+	return &natsStub{natstest.NewServer(t)}
+}
+
+func (s *natsStub) config() *NATS {
+	return &NATS{
+		Servers:       []string{s.URL()},
+		MaxReconnects: 0,
+		Timeout:       time.Second,
+		DrainTimeout:  time.Second,
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe to write to from connection callbacks.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+func TestNATSPoolShares(t *testing.T) {
+	stub := newNATSStub(t)
+	pool := &NATSPool{ClientName: "test"}
+
+	a, err := pool.Get(context.Background(), stub.config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := pool.Get(context.Background(), stub.config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a != b {
+		t.Fatal("want identical settings to share a connection")
+	}
+
+	other := stub.config()
+	other.Username = "other"
+
+	c, err := pool.Get(context.Background(), other)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c == a {
+		t.Fatal("want different settings to use another connection")
+	}
+
+	if stats := pool.Stats(); len(stats) != 2 {
+		t.Fatalf("stats = %d connections, want 2", len(stats))
+	}
+
+	err = pool.Release(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a.IsClosed() || a.IsDraining() {
+		t.Fatal("want the connection kept while still in use")
+	}
+
+	err = pool.Release(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitClosed(t, a)
+
+	err = pool.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !c.IsClosed() {
+		t.Fatal("want every connection closed by Close")
+	}
+}
+
+func TestNATSPoolLogger(t *testing.T) {
+	stub := newNATSStub(t)
+	buf := &syncBuffer{}
+
+	pool := &NATSPool{
+		ClientName: "test",
+		Logger:     slog.New(slog.NewJSONHandler(buf, nil)),
+	}
+
+	events := make(chan string, 10)
+	pool.OnEvent = func(e *NATSEvent) {
+		events <- e.Kind
+	}
+
+	// the connection outlives the task getting it first, so the attributes
+	// of its context aren't logged.
+	ctx := log.With(context.Background(), slog.String("task", "first"))
+
+	conn, err := pool.Get(ctx, stub.config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stub.Disconnect()
+	waitClosed(t, conn)
+
+	select {
+	case kind := <-events:
+		if kind != NATSDisconnected {
+			t.Errorf("event = %s, want %s", kind, NATSDisconnected)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+	}
+
+	logs := buf.String()
+	if !strings.Contains(logs, "nats_connection") || !strings.Contains(logs, "nats disconnected") {
+		t.Errorf("logs = %s, want the events with the connection", logs)
+	}
+
+	if strings.Contains(logs, "first") {
+		t.Errorf("logs = %s, want no attributes of the first context", logs)
+	}
+}
+
+func TestNATSPoolClosed(t *testing.T) {
+	stub := newNATSStub(t)
+	pool := &NATSPool{ClientName: "test", Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	conn, err := pool.Get(context.Background(), stub.config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	closed := pool.Closed(conn)
+
+	select {
+	case <-closed:
+		t.Fatal("want the connection open")
+	default:
+	}
+
+	// the connection closes without reconnecting, as MaxReconnects is 0.
+	stub.Disconnect()
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection not closed")
+	}
+
+	// released connections are closed.
+	err = pool.Release(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-pool.Closed(conn):
+	default:
+		t.Error("want a released connection closed")
+	}
+}
+
+func TestNATSPoolRunTask(t *testing.T) {
+	stub := newNATSStub(t)
+	pool := &NATSPool{ClientName: "test"}
+
+	conn, err := pool.Get(context.Background(), stub.config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = pool.RunTask(ctx)
+	if err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+
+	if !conn.IsClosed() {
+		t.Fatal("want the connections drained once stopped")
+	}
+
+	// releasing a connection drained by Close has no effect.
+	err = pool.Release(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func waitClosed(t *testing.T, c interface{ IsClosed() bool }) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !c.IsClosed() {
+		if time.Now().After(deadline) {
+			t.Fatal("connection not closed")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
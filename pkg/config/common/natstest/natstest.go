@@ -0,0 +1,110 @@
+// Package natstest provides a NATS server for tests of NATS clients.
+package natstest
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Server is a NATS server speaking just enough of the protocol for clients to
+// connect, flush and drain. It sends a nonce, so clients can authenticate with
+// NKeys, but doesn't deliver messages.
+type Server struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	conns    []net.Conn
+	connects []string
+}
+
+// NewServer returns a Server listening on a local port until the test ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{ln: ln}
+	t.Cleanup(s.Close)
+
+	go s.serve()
+
+	return s
+}
+
+// URL returns the URL clients connect to.
+func (s *Server) URL() string {
+	return "nats://" + s.ln.Addr().String()
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns = append(s.conns, conn)
+		s.mu.Unlock()
+
+		go func() {
+			defer conn.Close()
+
+			conn.Write([]byte("INFO {\"server_id\":\"stub\",\"version\":\"2.10.0\",\"max_payload\":1048576,\"proto\":1,\"nonce\":\"stub-nonce\"}\r\n"))
+
+			r := bufio.NewReader(conn)
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+
+				if strings.HasPrefix(line, "CONNECT ") {
+					s.mu.Lock()
+					s.connects = append(s.connects, strings.TrimSpace(strings.TrimPrefix(line, "CONNECT ")))
+					s.mu.Unlock()
+				}
+
+				if strings.HasPrefix(line, "PING") {
+					conn.Write([]byte("PONG\r\n"))
+				}
+			}
+		}()
+	}
+}
+
+// LastConnect returns the CONNECT message last sent by a client.
+func (s *Server) LastConnect() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.connects) == 0 {
+		return ""
+	}
+
+	return s.connects[len(s.connects)-1]
+}
+
+// Disconnect closes the connections of clients, which may reconnect.
+func (s *Server) Disconnect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.conns {
+		c.Close()
+	}
+
+	s.conns = nil
+}
+
+// Close stops accepting connections and closes those of clients.
+func (s *Server) Close() {
+	s.ln.Close()
+	s.Disconnect()
+}
@@ -0,0 +1,157 @@
+package common
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+
+	"github.com/svalevka/go/pkg/build"
+	"github.com/svalevka/go/pkg/config"
+)
+
+// StatsD configures a client writing metrics to a StatsD server, such as the
+// DataDog agent. Buffering and transport options are left to the client unless
+// configured.
+type StatsD struct {
+	// Host is the address of the StatsD server, either a host:port for UDP,
+	// or a Unix domain socket such as unix:///var/run/datadog/dsd.socket,
+	// where unixgram:// or unixstream:// force the type of socket.
+	Host string `yaml:"host" default:"localhost:8125" validate:"required"`
+
+	// Namespace is optionally prefixed to the name of every metric, such as
+	// myservice.
+	Namespace string `yaml:"namespace"`
+
+	// Env is optionally added to every metric as the env tag, such as prod.
+	Env string `yaml:"env"`
+
+	// HostTag adds the hostname to every metric as the host tag, which is
+	// otherwise added by the DataDog agent.
+	HostTag bool `yaml:"host_tag"`
+
+	// Tags are added to every metric, such as team:infra, in addition to the
+	// revision of the service.
+	Tags []string `yaml:"tags"`
+
+	// Aggregation is how metrics are aggregated before they are sent, either
+	// none, basic for gauges, counts and sets, or extended to also include
+	// histograms and distributions.
+	Aggregation string `yaml:"aggregation" default:"basic" validate:"oneof=none basic extended"`
+
+	// MaxBytesPerPayload is the maximum size of the packets sent.
+	MaxBytesPerPayload int `yaml:"max_bytes_per_payload" validate:"min=0"`
+
+	// MaxMessagesPerPayload is the maximum number of metrics in each packet.
+	MaxMessagesPerPayload int `yaml:"max_messages_per_payload" validate:"min=0"`
+
+	// BufferPoolSize is the number of packets buffered before metrics are
+	// dropped.
+	BufferPoolSize int `yaml:"buffer_pool_size" validate:"min=0"`
+
+	// BufferFlushInterval is how often partially filled packets are sent.
+	BufferFlushInterval time.Duration `yaml:"buffer_flush_interval" validate:"min=0s"`
+
+	// SenderQueueSize is the number of packets queued to be sent.
+	SenderQueueSize int `yaml:"sender_queue_size" validate:"min=0"`
+
+	// WriteTimeout is how long writing to a Unix domain socket may take.
+	WriteTimeout time.Duration `yaml:"write_timeout" validate:"min=0s"`
+
+	// Telemetry sends metrics about the client itself, such as the number of
+	// metrics dropped.
+	Telemetry bool `yaml:"telemetry"`
+}
+
+// Validate checks Host is either a host:port or a Unix domain socket.
+func (s *StatsD) Validate() error {
+	for _, prefix := range []string{statsd.UnixAddressPrefix, statsd.UnixAddressDatagramPrefix, statsd.UnixAddressStreamPrefix} {
+		if path, ok := strings.CutPrefix(s.Host, prefix); ok {
+			if path == "" {
+				return &config.ValidationError{Field: "host", Message: "must have the path of the socket"}
+			}
+
+			return nil
+		}
+	}
+
+	_, _, err := net.SplitHostPort(s.Host)
+	if s.Host != "" && err != nil {
+		return &config.ValidationError{Field: "host", Message: fmt.Sprintf("must be a host:port address or unix:// path, got %q", s.Host)}
+	}
+
+	return nil
+}
+
+// Client returns a StatsD client configured by StatsD, which must be closed to
+// send any buffered metrics.
+func (s *StatsD) Client() (*statsd.Client, error) {
+	tags := append([]string{}, s.Tags...)
+
+	if rev := build.GetRevision(7); rev != "" {
+		tags = append(tags, "revision:"+rev)
+	}
+
+	if s.Env != "" {
+		tags = append(tags, "env:"+s.Env)
+	}
+
+	if s.HostTag {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("host tag: %w", err)
+		}
+
+		tags = append(tags, "host:"+hostname)
+	}
+
+	opts := []statsd.Option{
+		statsd.WithTags(tags),
+	}
+
+	if s.Namespace != "" {
+		opts = append(opts, statsd.WithNamespace(s.Namespace))
+	}
+
+	switch s.Aggregation {
+	case "none":
+		opts = append(opts, statsd.WithoutClientSideAggregation())
+	case "extended":
+		opts = append(opts, statsd.WithExtendedClientSideAggregation())
+	default:
+		opts = append(opts, statsd.WithClientSideAggregation())
+	}
+
+	if s.MaxBytesPerPayload > 0 {
+		opts = append(opts, statsd.WithMaxBytesPerPayload(s.MaxBytesPerPayload))
+	}
+
+	if s.MaxMessagesPerPayload > 0 {
+		opts = append(opts, statsd.WithMaxMessagesPerPayload(s.MaxMessagesPerPayload))
+	}
+
+	if s.BufferPoolSize > 0 {
+		opts = append(opts, statsd.WithBufferPoolSize(s.BufferPoolSize))
+	}
+
+	if s.BufferFlushInterval > 0 {
+		opts = append(opts, statsd.WithBufferFlushInterval(s.BufferFlushInterval))
+	}
+
+	if s.SenderQueueSize > 0 {
+		opts = append(opts, statsd.WithSenderQueueSize(s.SenderQueueSize))
+	}
+
+	if s.WriteTimeout > 0 {
+		opts = append(opts, statsd.WithWriteTimeout(s.WriteTimeout))
+	}
+
+	if !s.Telemetry {
+		opts = append(opts, statsd.WithoutTelemetry())
+	}
+
+	return statsd.New(s.Host, opts...)
+}
@@ -0,0 +1,123 @@
+package common
+
+import (
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/svalevka/go/pkg/build"
+	"github.com/svalevka/go/pkg/config"
+)
+
+// statsdPackets listens for StatsD packets on UDP until the test ends,
+// returning the address and the channel given each packet.
+func statsdPackets(t *testing.T) (string, chan string) {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	packets := make(chan string, 10)
+
+	go func() {
+		b := make([]byte, 65536)
+		for {
+			n, _, err := conn.ReadFrom(b)
+			if err != nil {
+				return
+			}
+
+			packets <- string(b[:n])
+		}
+	}()
+
+	return conn.LocalAddr().String(), packets
+}
+
+func TestStatsDClient(t *testing.T) {
+	addr, packets := statsdPackets(t)
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &StatsD{
+		Host:        addr,
+		Namespace:   "myservice.",
+		Env:         "prod",
+		HostTag:     true,
+		Tags:        []string{"team:infra"},
+		Aggregation: "none",
+	}
+
+	err = config.Validate(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := s.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = client.Count("requests", 1, []string{"code:200"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// closing sends the buffered metrics.
+	err = client.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case packet := <-packets:
+		tags := "team:infra,"
+		if rev := build.GetRevision(7); rev != "" {
+			tags += "revision:" + rev + ","
+		}
+
+		// telemetry is disabled by default, so only the count is sent.
+		want := "myservice.requests:1|c|#" + tags + "env:prod,host:" + hostname + ",code:200\n"
+		if packet != want {
+			t.Errorf("packet = %q, want %q", packet, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no metrics sent")
+	}
+}
+
+func TestStatsDValidation(t *testing.T) {
+	for _, tt := range []struct {
+		statsd StatsD
+		want   string
+	}{
+		{StatsD{}, ""},
+		{StatsD{Host: "unix:///var/run/datadog/dsd.socket"}, ""},
+		{StatsD{Host: "unixstream:///var/run/datadog/dsd.socket"}, ""},
+		{StatsD{Host: "unixgram://"}, "host: must have the path of the socket"},
+		{StatsD{Host: "localhost"}, `host: must be a host:port address or unix:// path, got "localhost"`},
+		{StatsD{Aggregation: "all"}, "aggregation: must be one of none, basic, extended"},
+		{StatsD{BufferPoolSize: -1}, "buffer_pool_size:"},
+	} {
+		s := tt.statsd
+
+		err := config.SetDefaults(&s)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = config.Validate(&s)
+		if tt.want == "" && err != nil || tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("Validate(%+v) = %v, want %q", tt.statsd, err, tt.want)
+		}
+	}
+}
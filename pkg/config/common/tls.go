@@ -0,0 +1,315 @@
+package common
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/svalevka/go/pkg/config"
+)
+
+// ClientTLS configures the TLS connections of clients, where the certificate
+// files are reloaded when they change, so they can be renewed without a
+// restart.
+type ClientTLS struct {
+	// CA is the path of a PEM bundle of certificate authorities trusted to
+	// verify servers, instead of those of the system.
+	CA string `yaml:"ca"`
+
+	// Cert is the path of a PEM client certificate presented to servers,
+	// which requires Key.
+	Cert string `yaml:"cert"`
+
+	// Key is the path of the PEM private key of Cert.
+	Key string `yaml:"key"`
+
+	// ServerName overrides the name the certificates of servers are verified
+	// against, which is otherwise the host connected to. It is required with
+	// CA to connect to servers by IP address, such as 10.0.0.5.
+	ServerName string `yaml:"server_name"`
+}
+
+// Validate checks Cert and Key are configured together.
+func (t *ClientTLS) Validate() error {
+	if (t.Cert == "") != (t.Key == "") {
+		return &config.ValidationError{Message: "cert and key must be configured together"}
+	}
+
+	return nil
+}
+
+// Enabled returns whether any TLS settings are configured.
+func (t *ClientTLS) Enabled() bool {
+	return *t != ClientTLS{}
+}
+
+// Config returns a tls.Config configured by ClientTLS, which reloads the
+// certificate files when they are modified. An error is returned if the files
+// can't be loaded initially, while errors reloading them keep the previously
+// loaded files.
+func (t *ClientTLS) Config() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: t.ServerName,
+	}
+
+	if t.Cert != "" {
+		kp := &keyPair{certFile: t.Cert, keyFile: t.Key}
+
+		_, err := kp.load()
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return kp.load()
+		}
+	}
+
+	if t.CA != "" {
+		ca := &certPool{file: t.CA}
+
+		_, err := ca.load()
+		if err != nil {
+			return nil, err
+		}
+
+		serverName := t.ServerName
+
+		// the standard verification can't reload its roots, so servers are
+		// verified by VerifyConnection instead. Its ServerName is only the
+		// name sent to servers, which is empty for IP addresses, so they
+		// require ServerName to be configured.
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			name := serverName
+			if name == "" {
+				name = cs.ServerName
+			}
+
+			if name == "" {
+				return errors.New("tls: server_name is required to verify servers connected to by IP address")
+			}
+
+			roots, err := ca.load()
+			if err != nil {
+				return err
+			}
+
+			return verifyPeer(cs, roots, name, x509.ExtKeyUsageServerAuth)
+		}
+	}
+
+	return cfg, nil
+}
+
+// ServerTLS configures the TLS of servers, where the certificate files are
+// reloaded when they change, so they can be renewed without a restart.
+type ServerTLS struct {
+	// Cert is the path of the PEM certificate of the server, which enables
+	// TLS and requires Key.
+	Cert string `yaml:"cert"`
+
+	// Key is the path of the PEM private key of Cert.
+	Key string `yaml:"key"`
+
+	// ClientCA is the path of a PEM bundle of certificate authorities, which
+	// requires clients to present a certificate they issued.
+	ClientCA string `yaml:"client_ca"`
+}
+
+// Validate checks Cert and Key are configured together, and ClientCA is only
+// configured with them.
+func (t *ServerTLS) Validate() error {
+	if (t.Cert == "") != (t.Key == "") {
+		return &config.ValidationError{Message: "cert and key must be configured together"}
+	}
+
+	if t.ClientCA != "" && t.Cert == "" {
+		return &config.ValidationError{Field: "client_ca", Message: "requires cert and key to be configured"}
+	}
+
+	return nil
+}
+
+// Enabled returns whether TLS is configured.
+func (t *ServerTLS) Enabled() bool {
+	return t.Cert != ""
+}
+
+// Config returns a tls.Config configured by ServerTLS, which reloads the
+// certificate files when they are modified. An error is returned if the files
+// can't be loaded initially, while errors reloading them keep the previously
+// loaded files.
+func (t *ServerTLS) Config() (*tls.Config, error) {
+	kp := &keyPair{certFile: t.Cert, keyFile: t.Key}
+
+	_, err := kp.load()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return kp.load()
+		},
+	}
+
+	if t.ClientCA != "" {
+		ca := &certPool{file: t.ClientCA}
+
+		_, err := ca.load()
+		if err != nil {
+			return nil, err
+		}
+
+		// the standard verification can't reload its roots, so clients are
+		// verified by VerifyConnection instead.
+		cfg.ClientAuth = tls.RequireAnyClientCert
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			roots, err := ca.load()
+			if err != nil {
+				return err
+			}
+
+			return verifyPeer(cs, roots, "", x509.ExtKeyUsageClientAuth)
+		}
+	}
+
+	return cfg, nil
+}
+
+// verifyPeer verifies the certificate chain of a connection against roots,
+// and against name if not empty.
+func verifyPeer(cs tls.ConnectionState, roots *x509.CertPool, name string, usage x509.ExtKeyUsage) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("tls: no peer certificate")
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		DNSName:       name,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	}
+
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	if err != nil {
+		return fmt.Errorf("tls: %w", err)
+	}
+
+	return nil
+}
+
+// keyPair loads a certificate and private key, reloading them when either file
+// is modified.
+type keyPair struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// load returns the certificate, reloading it if the files have been modified
+// since it was last loaded.
+func (k *keyPair) load() (*tls.Certificate, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	modTime, err := latestModTime(k.certFile, k.keyFile)
+	if err == nil && k.cert != nil && modTime.Equal(k.modTime) {
+		return k.cert, nil
+	}
+
+	var cert tls.Certificate
+	if err == nil {
+		cert, err = tls.LoadX509KeyPair(k.certFile, k.keyFile)
+	}
+
+	if err != nil {
+		// keep the loaded certificate, such as while its files are being
+		// replaced.
+		if k.cert != nil {
+			return k.cert, nil
+		}
+
+		return nil, fmt.Errorf("load certificate: %w", err)
+	}
+
+	k.cert, k.modTime = &cert, modTime
+
+	return k.cert, nil
+}
+
+// certPool loads a bundle of certificate authorities, reloading it when the
+// file is modified.
+type certPool struct {
+	file string
+
+	mu      sync.Mutex
+	pool    *x509.CertPool
+	modTime time.Time
+}
+
+// load returns the certificate pool, reloading it if the file has been
+// modified since it was last loaded.
+func (c *certPool) load() (*x509.CertPool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	modTime, err := latestModTime(c.file)
+	if err == nil && c.pool != nil && modTime.Equal(c.modTime) {
+		return c.pool, nil
+	}
+
+	var pem []byte
+	if err == nil {
+		pem, err = os.ReadFile(c.file)
+	}
+
+	pool := x509.NewCertPool()
+	if err == nil && !pool.AppendCertsFromPEM(pem) {
+		err = fmt.Errorf("no certificates found in %s", c.file)
+	}
+
+	if err != nil {
+		if c.pool != nil {
+			return c.pool, nil
+		}
+
+		return nil, fmt.Errorf("load certificate authorities: %w", err)
+	}
+
+	c.pool, c.modTime = pool, modTime
+
+	return c.pool, nil
+}
+
+// latestModTime returns the latest modification time of the files.
+func latestModTime(files ...string) (time.Time, error) {
+	latest := time.Time{}
+
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest, nil
+}
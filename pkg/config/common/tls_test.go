@@ -0,0 +1,344 @@
+package common
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCA is a certificate authority issuing certificates for tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+
+	// file is the path of the PEM certificate of the authority.
+	file string
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(t.TempDir(), "ca.pem")
+	writePEM(t, file, "CERTIFICATE", der)
+
+	return &testCA{cert: cert, key: key, file: file}
+}
+
+// issue writes a certificate for name, a host or IP address, with usage and
+// its key to files in dir, returning their paths.
+func (ca *testCA) issue(t *testing.T, dir, name string, usage x509.ExtKeyUsage) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+
+	if ip := net.ParseIP(name); ip != nil {
+		tmpl.DNSNames, tmpl.IPAddresses = nil, []net.IP{ip}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, path, typ string, der []byte) {
+	t.Helper()
+
+	err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// touch sets the modification time of the files to d from now, so they are
+// reloaded regardless of the resolution of modification times.
+func touch(t *testing.T, d time.Duration, files ...string) {
+	t.Helper()
+
+	for _, f := range files {
+		err := os.Chtimes(f, time.Now().Add(d), time.Now().Add(d))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// handshake performs a TLS handshake between client and server, returning the
+// error of the client and the certificates presented by it.
+func handshake(t *testing.T, client, server *tls.Config) ([]*x509.Certificate, error) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	done := make(chan []*x509.Certificate, 1)
+	go func() {
+		s, err := ln.Accept()
+		if err != nil {
+			done <- nil
+			return
+		}
+		defer s.Close()
+
+		// the server fails with the client, whose error is returned.
+		conn := tls.Server(s, server)
+		conn.Handshake()
+		done <- conn.ConnectionState().PeerCertificates
+	}()
+
+	c, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tls.Client(c, client).Handshake()
+	c.Close()
+
+	return <-done, err
+}
+
+func TestClientTLS(t *testing.T) {
+	ca := newTestCA(t)
+
+	serverCert, serverKey := ca.issue(t, t.TempDir(), "nats.test", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, t.TempDir(), "client", x509.ExtKeyUsageClientAuth)
+
+	server, err := (&ServerTLS{Cert: serverCert, Key: serverKey}).Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.ClientAuth = tls.RequestClientCert
+
+	c := &ClientTLS{CA: ca.file, Cert: clientCert, Key: clientKey, ServerName: "nats.test"}
+
+	client, err := c.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peers, err := handshake(t, client, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(peers) != 1 || peers[0].Subject.CommonName != "client" {
+		t.Errorf("peers = %v, want the client certificate presented", peers)
+	}
+
+	// servers are verified against the name and authorities.
+	c.ServerName = "other.test"
+
+	client, err = c.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = handshake(t, client, server)
+	if err == nil || !strings.Contains(err.Error(), "other.test") {
+		t.Errorf("err = %v, want the server name verified", err)
+	}
+
+	client, err = (&ClientTLS{CA: newTestCA(t).file, ServerName: "nats.test"}).Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = handshake(t, client, server)
+	if err == nil || !strings.Contains(err.Error(), "unknown authority") {
+		t.Errorf("err = %v, want the authority verified", err)
+	}
+}
+
+func TestClientTLSIPAddress(t *testing.T) {
+	ca := newTestCA(t)
+
+	wrongCert, wrongKey := ca.issue(t, t.TempDir(), "evil.example", x509.ExtKeyUsageServerAuth)
+	ipCert, ipKey := ca.issue(t, t.TempDir(), "10.0.0.5", x509.ExtKeyUsageServerAuth)
+
+	wrong, err := (&ServerTLS{Cert: wrongCert, Key: wrongKey}).Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ip, err := (&ServerTLS{Cert: ipCert, Key: ipKey}).Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// clients such as NATS and net/http set the ServerName of a copy of the
+	// config to the host connected to, which isn't sent for IP addresses.
+	dial := func(c *ClientTLS) *tls.Config {
+		t.Helper()
+
+		cfg, err := c.Config()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cfg = cfg.Clone()
+		if cfg.ServerName == "" {
+			cfg.ServerName = "10.0.0.5"
+		}
+
+		return cfg
+	}
+
+	for _, tt := range []struct {
+		client *ClientTLS
+		server *tls.Config
+		want   string
+	}{
+		{&ClientTLS{CA: ca.file}, wrong, "server_name is required"},
+		{&ClientTLS{CA: ca.file}, ip, "server_name is required"},
+		{&ClientTLS{CA: ca.file, ServerName: "10.0.0.5"}, wrong, "doesn't contain any IP SANs"},
+		{&ClientTLS{CA: ca.file, ServerName: "10.0.0.5"}, ip, ""},
+	} {
+		_, err := handshake(t, dial(tt.client), tt.server)
+		if tt.want == "" && err != nil || tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("handshake(%+v) = %v, want %q", tt.client, err, tt.want)
+		}
+	}
+}
+
+func TestClientTLSReloads(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+
+	cert, key := ca.issue(t, dir, "first", x509.ExtKeyUsageClientAuth)
+
+	client, err := (&ClientTLS{Cert: cert, Key: key}).Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ca.issue(t, dir, "second", x509.ExtKeyUsageClientAuth)
+	touch(t, time.Minute, cert, key)
+
+	if name := clientCertName(t, client); name != "second" {
+		t.Errorf("certificate = %s, want reloaded", name)
+	}
+
+	// invalid files keep the loaded certificate.
+	err = os.WriteFile(cert, []byte("invalid"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	touch(t, 2*time.Minute, cert)
+
+	if name := clientCertName(t, client); name != "second" {
+		t.Errorf("certificate = %s, want kept", name)
+	}
+}
+
+// clientCertName returns the common name of the client certificate of cfg.
+func clientCertName(t *testing.T, cfg *tls.Config) string {
+	t.Helper()
+
+	cert, err := cfg.GetClientCertificate(&tls.CertificateRequestInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return leaf.Subject.CommonName
+}
+
+func TestClientTLSErrors(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.pem")
+
+	err := os.WriteFile(empty, nil, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		tls  ClientTLS
+		want string
+	}{
+		{ClientTLS{CA: filepath.Join(dir, "missing.pem")}, "load certificate authorities"},
+		{ClientTLS{CA: empty}, "no certificates found in " + empty},
+		{ClientTLS{Cert: empty, Key: empty}, "load certificate"},
+	} {
+		_, err := tt.tls.Config()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Config(%+v) = %v, want %q", tt.tls, err, tt.want)
+		}
+	}
+
+	err = (&ClientTLS{Cert: "cert.pem"}).Validate()
+	if err == nil || !strings.Contains(err.Error(), "cert and key must be configured together") {
+		t.Errorf("err = %v, want cert and key required together", err)
+	}
+}
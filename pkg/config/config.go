@@ -1,32 +1,223 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"reflect"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
+// Loader reads configuration files, overlaying additional sources of
+// configuration before the result is validated.
+type Loader struct {
+	// EnvPrefix optionally enables overlaying environment variables that
+	// begin with the prefix onto the configuration, see ApplyEnv.
+	EnvPrefix string
+
+	// Flags optionally overlays command-line flags onto the configuration,
+	// after environment variables, see NewFlags.
+	Flags *Flags
+
+	// Environ optionally overrides the source of environment variables, which
+	// defaults to os.Environ.
+	Environ func() []string
+
+	// Resolvers optionally adds or overrides the schemes of references that
+	// are interpolated into configuration values, keyed by scheme. The env,
+	// file and cred schemes are builtin, see FromFile.
+	Resolvers map[string]Resolver
+
+	// Slices configures how sequences are merged when loading multiple
+	// files, which defaults to SliceReplace. It can be overridden for a
+	// single sequence with the TagAppend and TagReplace YAML tags.
+	Slices SliceStrategy
+
+	// AllowUnknownFields disables strict decoding, where keys that don't
+	// match a field of the configuration type are reported as errors, such
+	// as those with typos. Duplicate keys and values of the wrong type are
+	// always reported.
+	AllowUnknownFields bool
+}
+
 // FromFile unmarshals a JSON or YAML configuration file from the given path,
-// optionally performing validation if the Validator interface is implemented.
+// then applies defaults and performs validation, see SetDefaults and Validate.
 func FromFile(path string, dst any) error {
+	return (&Loader{}).FromFile(path, dst)
+}
+
+// FromFile unmarshals a JSON or YAML configuration file from the given path,
+// then overlays environment variables and command-line flags if configured,
+// applies defaults, lastly performing validation, see SetDefaults and
+// Validate.
+//
+// References in values of the file and environment variables are replaced
+// with the value they refer to, where $${ escapes a literal ${:
+//
+//	${env:NAME}                  the environment variable NAME.
+//	${file:/run/secrets/nats}    the contents of a file.
+//	${cred:nats}                 the systemd credential nats, read from
+//	                             $CREDENTIALS_DIRECTORY.
+//
+// Fields holding resolved secrets should use the Secret type, so they are
+// never revealed in logs or dumps of the configuration.
+func (l *Loader) FromFile(path string, dst any) error {
+	_, err := l.FromFiles([]string{path}, dst)
+	return err
+}
+
+// FromFiles is equivalent to FromFile, but deep merges multiple files in order,
+// such that later files override earlier ones. Paths that are directories are
+// expanded into the JSON and YAML files they contain, sorted by name, like a
+// conf.d directory.
+//
+// Mappings are merged by key, sequences are merged according to the Slices
+// strategy, and everything else is replaced. The returned Origins record where
+// each value of the result was loaded from.
+//
+// Files of older versions of the configuration are upgraded before they are
+// merged, see Migrator. The top-level version key of each file is reserved for
+// this purpose, and defaults to 1 when missing.
+//
+// Unless AllowUnknownFields is set, decoding is strict, so unknown keys are
+// reported along with duplicate keys and values of the wrong type. All such
+// problems are returned together as DecodeErrors, with their file, line,
+// column and field path.
+//
+// Defaults are only applied to fields left unset by the files, environment
+// variables and flags, so fields with defaults can still be configured as
+// zero, such as max_reconnects: 0.
+func (l *Loader) FromFiles(paths []string, dst any) (Origins, error) {
+	files, err := expandPaths(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc *yaml.Node
+	nodeFiles := map[*yaml.Node]string{}
+
+	c := &checker{
+		files:        nodeFiles,
+		allowUnknown: l.AllowUnknownFields,
+	}
+
+	for _, path := range files {
+		n, err := l.readFile(path, dst)
+		if err != nil {
+			return nil, err
+		}
+
+		if n == nil {
+			continue
+		}
+
+		recordFile(n, path, nodeFiles)
+		c.duplicates(n, "")
+
+		doc = merge(doc, n, l.Slices)
+	}
+
+	origins := Origins{}
+
+	if doc != nil {
+		c.check(reflect.TypeOf(dst), doc, "")
+		if len(c.errs) > 0 {
+			return nil, c.errs
+		}
+
+		nodeOrigins(doc, nodeFiles, origins)
+
+		err = doc.Decode(dst)
+		if err != nil {
+			return nil, fmt.Errorf("yaml: %w", err)
+		}
+	}
+
+	if l.EnvPrefix != "" {
+		prefix := strings.ToUpper(l.EnvPrefix) + EnvSeparator
+		environ := []string{}
+
+		for _, kv := range l.environ() {
+			k, v, _ := strings.Cut(kv, "=")
+			if !strings.HasPrefix(strings.ToUpper(k), prefix) {
+				continue
+			}
+
+			v, err = l.interpolate(v)
+			if err != nil {
+				return nil, fmt.Errorf("interpolate: env %s: %w", k, err)
+			}
+
+			environ = append(environ, k+"="+v)
+		}
+
+		err = applyEnv(l.EnvPrefix, environ, dst, origins)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if l.Flags != nil {
+		err = l.Flags.apply(dst, origins)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = setLoadedDefaults(dst, origins)
+	if err != nil {
+		return nil, err
+	}
+
+	err = Validate(dst)
+	if err != nil {
+		return nil, err
+	}
+
+	return origins, nil
+}
+
+// readFile parses, migrates and interpolates the YAML document in the file at
+// path for the configuration type of dst, or returns nil if the file is empty.
+func (l *Loader) readFile(path string, dst any) (*yaml.Node, error) {
 	file, err := os.OpenFile(path, os.O_RDONLY, 0)
 	if err != nil {
-		return fmt.Errorf("open: %w", err)
+		return nil, fmt.Errorf("open: %w", err)
 	}
 	defer file.Close()
 
-	err = yaml.NewDecoder(file).Decode(dst)
+	doc := &yaml.Node{}
+
+	err = yaml.NewDecoder(file).Decode(doc)
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("yaml: %s: %w", path, err)
+	}
+
+	_, err = Migrate(doc, dst)
 	if err != nil {
-		return fmt.Errorf("yaml: %w", err)
+		return nil, fmt.Errorf("migrate: %s: %w", path, err)
 	}
 
-	if v, ok := dst.(Validator); ok {
-		err = v.Validate()
-		if err != nil {
-			return err
-		}
+	removeVersion(doc)
+
+	err = l.interpolateNode(path, doc)
+	if err != nil {
+		return nil, fmt.Errorf("interpolate: %w", err)
+	}
+
+	return doc, nil
+}
+
+// environ returns the environment variables available to the Loader.
+func (l *Loader) environ() []string {
+	if l.Environ != nil {
+		return l.Environ()
 	}
 
-	return nil
+	return os.Environ()
 }
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// Defaulter is optionally implemented by configuration types that compute
+// default values, which can't be expressed by default tags. SetDefaults is
+// called after the default tags of the type have been applied.
+type Defaulter interface {
+	SetDefaults()
+}
+
+// SetDefaults walks the configuration v, including nested structs, pointers,
+// slices and maps, setting every field that has the zero value to the value of
+// its default tag, then calling the SetDefaults method of every type
+// implementing Defaulter.
+//
+// Default tags are decoded as YAML, for example `default:"localhost:8125"`,
+// `default:"5s"` or `default:"[localhost:8080]"`. A nil pointer to a struct
+// is allocated by `default:"{}"`, so the defaults of its fields are applied.
+//
+// As defaults are only applied to zero values, a bool with a default of true
+// can't be set to false by SetDefaults alone, so options should be named such
+// that false is the default. A Loader leaves fields that were configured,
+// including those configured as zero.
+func SetDefaults(v any) error {
+	return setLoadedDefaults(v, nil)
+}
+
+// setLoadedDefaults is equivalent to SetDefaults, but leaves the fields
+// configured by the origins of a Loader, so they can be set to zero.
+func setLoadedDefaults(v any, origins Origins) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("defaults: destination must be a non-nil pointer, got %T", v)
+	}
+
+	return setDefaults(rv, "", origins)
+}
+
+// setDefaults applies defaults to v at path and everything it contains,
+// except the default tags of fields whose path is in set, as they were
+// configured explicitly.
+func setDefaults(v reflect.Value, path string, set Origins) error {
+	errs := []error{}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+
+		return setDefaults(v.Elem(), path, set)
+
+	case reflect.Struct:
+		errs = append(errs, setStructDefaults(v, path, set))
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			err := setDefaults(v.Index(i), path+"["+strconv.Itoa(i)+"]", set)
+			if err != nil {
+				errs = append(errs, wrapPath(fmt.Sprintf("[%d]", i), err))
+			}
+		}
+
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			// map elements are not addressable, so modify a copy of the
+			// element and store it back.
+			elem := reflect.New(iter.Value().Type()).Elem()
+			elem.Set(iter.Value())
+
+			err := setDefaults(elem, joinPath(path, fmt.Sprint(iter.Key().Interface())), set)
+			if err != nil {
+				errs = append(errs, wrapPath(fmt.Sprint(iter.Key().Interface()), err))
+			}
+
+			v.SetMapIndex(iter.Key(), elem)
+		}
+	}
+
+	if v.CanAddr() {
+		if d, ok := v.Addr().Interface().(Defaulter); ok {
+			d.SetDefaults()
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// setStructDefaults applies the default tags of the fields of struct v at path,
+// then the defaults of the field values themselves.
+func setStructDefaults(v reflect.Value, path string, set Origins) error {
+	errs := []error{}
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		name, inline, skip := yamlName(t.Field(i))
+		if skip {
+			continue
+		}
+
+		fv := v.Field(i)
+
+		fieldPath := path
+		if !inline {
+			fieldPath = joinPath(path, name)
+		}
+
+		_, configured := set[fieldPath]
+
+		if def, ok := t.Field(i).Tag.Lookup("default"); ok && !inline && !configured && fv.IsZero() {
+			err := setValue(fv, def)
+			if err != nil {
+				errs = append(errs, &pathError{path: name, err: fmt.Errorf("invalid default %q: %w", def, err)})
+				continue
+			}
+		}
+
+		err := setDefaults(fv, fieldPath, set)
+		if err != nil {
+			if !inline {
+				err = wrapPath(name, err)
+			}
+
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type defaultsServer struct {
+	Addr    string        `yaml:"addr" default:"localhost:8080"`
+	Retries int           `yaml:"retries" default:"3"`
+	Timeout time.Duration `yaml:"timeout" default:"5s"`
+}
+
+type defaultsConfig struct {
+	Server  defaultsServer            `yaml:"server"`
+	Servers []defaultsServer          `yaml:"servers"`
+	Named   map[string]defaultsServer `yaml:"named"`
+	Pointer *defaultsServer           `yaml:"pointer" default:"{}"`
+	Hosts   []string                  `yaml:"hosts" default:"[a, b]"`
+	Limit   *defaultsLimit            `yaml:"limit"`
+}
+
+type defaultsLimit struct {
+	Max int `yaml:"max"`
+}
+
+// SetDefaults computes a default which can't be expressed by a tag.
+func (l *defaultsLimit) SetDefaults() {
+	if l.Max == 0 {
+		l.Max = 100
+	}
+}
+
+func TestSetDefaults(t *testing.T) {
+	cfg := &defaultsConfig{
+		Servers: []defaultsServer{{Addr: "a:1"}},
+		Named:   map[string]defaultsServer{"x": {Retries: 5}},
+		Limit:   &defaultsLimit{},
+	}
+
+	err := SetDefaults(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := defaultsServer{Addr: "localhost:8080", Retries: 3, Timeout: 5 * time.Second}
+	if cfg.Server != want {
+		t.Errorf("server = %+v, want %+v", cfg.Server, want)
+	}
+
+	if s := cfg.Servers[0]; s.Addr != "a:1" || s.Retries != 3 {
+		t.Errorf("servers[0] = %+v, want the addr kept and defaults applied", s)
+	}
+
+	if s := cfg.Named["x"]; s.Retries != 5 || s.Timeout != 5*time.Second {
+		t.Errorf("named.x = %+v, want the retries kept and defaults applied", s)
+	}
+
+	if cfg.Pointer == nil || *cfg.Pointer != want {
+		t.Errorf("pointer = %+v, want allocated with defaults", cfg.Pointer)
+	}
+
+	if len(cfg.Hosts) != 2 {
+		t.Errorf("hosts = %v, want [a b]", cfg.Hosts)
+	}
+
+	if cfg.Limit.Max != 100 {
+		t.Errorf("limit.max = %d, want 100 from Defaulter", cfg.Limit.Max)
+	}
+}
+
+func TestSetDefaultsInvalid(t *testing.T) {
+	type invalid struct {
+		Port int `yaml:"port" default:"http"`
+	}
+
+	err := SetDefaults(&invalid{})
+	if err == nil {
+		t.Fatal("want an error for an invalid default")
+	}
+
+	err = SetDefaults(invalid{})
+	if err == nil {
+		t.Fatal("want an error for a non-pointer destination")
+	}
+}
+
+func TestLoaderKeepsConfiguredZero(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+
+	err := os.WriteFile(path, []byte("server:\n  retries: 0\nservers:\n- timeout: 0s\nhosts: []\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &defaultsConfig{}
+
+	err = (&Loader{
+		EnvPrefix: "TEST",
+		Environ:   func() []string { return []string{"TEST__POINTER__RETRIES=0"} },
+	}).FromFile(path, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Server.Retries != 0 || cfg.Server.Timeout != 5*time.Second {
+		t.Errorf("server = %+v, want retries kept zero and timeout defaulted", cfg.Server)
+	}
+
+	if s := cfg.Servers[0]; s.Timeout != 0 || s.Retries != 3 {
+		t.Errorf("servers[0] = %+v, want timeout kept zero and retries defaulted", s)
+	}
+
+	if cfg.Pointer.Retries != 0 || cfg.Pointer.Addr != "localhost:8080" {
+		t.Errorf("pointer = %+v, want retries kept zero by the environment", cfg.Pointer)
+	}
+
+	if len(cfg.Hosts) != 0 {
+		t.Errorf("hosts = %v, want empty", cfg.Hosts)
+	}
+}
@@ -0,0 +1,209 @@
+// Command docgen generates a file registering the doc comments of the struct
+// types in a package with config.RegisterDocs, so configuration can be
+// described at runtime. It is intended to be run by go generate:
+//
+//	//go:generate go run github.com/svalevka/go/pkg/config/docgen
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// output is the name of the generated file.
+const output = "config_docs.go"
+
+func main() {
+	err := run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "docgen: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	pkgPath, err := exec.Command("go", "list", "-f", "{{.ImportPath}}", ".").Output()
+	if err != nil {
+		return fmt.Errorf("go list: %w", err)
+	}
+
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		return err
+	}
+
+	fset := token.NewFileSet()
+	pkgName := ""
+	comments := map[string]string{}
+
+	for _, name := range files {
+		if name == output || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, name, nil, parser.ParseComments)
+		if err != nil {
+			return err
+		}
+
+		pkgName = file.Name.Name
+		collect(file, comments)
+	}
+
+	if len(comments) == 0 {
+		return fmt.Errorf("no documented struct types found")
+	}
+
+	src, err := generate(pkgName, strings.TrimSpace(string(pkgPath)), comments)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(output, src, 0o644)
+}
+
+// collect adds the doc comments of exported struct types with yaml tagged
+// fields, and the doc comments of their fields, in file to comments.
+func collect(file *ast.File, comments map[string]string) {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			if !ts.Name.IsExported() {
+				continue
+			}
+
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok || !hasYAMLTags(st) {
+				continue
+			}
+
+			doc := ts.Doc
+			if doc == nil && len(gen.Specs) == 1 {
+				doc = gen.Doc
+			}
+
+			if text := clean(doc); text != "" {
+				comments[ts.Name.Name] = text
+			}
+
+			for _, field := range st.Fields.List {
+				doc := field.Doc
+				if doc == nil {
+					doc = field.Comment
+				}
+
+				text := clean(doc)
+				if text == "" {
+					continue
+				}
+
+				for _, name := range fieldNames(field) {
+					comments[ts.Name.Name+"."+name] = text
+				}
+			}
+		}
+	}
+}
+
+// hasYAMLTags returns whether any field of st has a yaml tag, identifying it
+// as a configuration type.
+func hasYAMLTags(st *ast.StructType) bool {
+	for _, field := range st.Fields.List {
+		if field.Tag != nil && strings.Contains(field.Tag.Value, "yaml:") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fieldNames returns the names of the exported fields declared by field,
+// including the type name of embedded fields.
+func fieldNames(field *ast.Field) []string {
+	names := []string{}
+
+	if len(field.Names) == 0 {
+		expr := field.Type
+		if star, ok := expr.(*ast.StarExpr); ok {
+			expr = star.X
+		}
+
+		switch t := expr.(type) {
+		case *ast.Ident:
+			names = append(names, t.Name)
+		case *ast.SelectorExpr:
+			names = append(names, t.Sel.Name)
+		}
+	}
+
+	for _, name := range field.Names {
+		names = append(names, name.Name)
+	}
+
+	exported := []string{}
+	for _, name := range names {
+		if ast.IsExported(name) {
+			exported = append(exported, name)
+		}
+	}
+
+	return exported
+}
+
+// clean returns the text of a comment with lines of each paragraph joined.
+func clean(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+
+	paragraphs := []string{}
+	for _, p := range strings.Split(strings.TrimSpace(doc.Text()), "\n\n") {
+		paragraphs = append(paragraphs, strings.Join(strings.Fields(p), " "))
+	}
+
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// generate returns the formatted source of the generated file.
+func generate(pkgName, pkgPath string, comments map[string]string) ([]byte, error) {
+	keys := make([]string, 0, len(comments))
+	for key := range comments {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	b := &bytes.Buffer{}
+	b.WriteString("// Code generated by docgen. DO NOT EDIT.\n\n")
+	b.WriteString("package " + pkgName + "\n\n")
+
+	if pkgPath != "github.com/svalevka/go/pkg/config" {
+		b.WriteString("import \"github.com/svalevka/go/pkg/config\"\n\n")
+		b.WriteString("func init() {\n\tconfig.RegisterDocs(" + strconv.Quote(pkgPath) + ", map[string]string{\n")
+	} else {
+		b.WriteString("func init() {\n\tRegisterDocs(" + strconv.Quote(pkgPath) + ", map[string]string{\n")
+	}
+
+	for _, key := range keys {
+		b.WriteString("\t\t" + strconv.Quote(key) + ": " + strconv.Quote(comments[key]) + ",\n")
+	}
+
+	b.WriteString("\t})\n}\n")
+
+	return format.Source(b.Bytes())
+}
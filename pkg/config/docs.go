@@ -0,0 +1,59 @@
+package config
+
+import (
+	"reflect"
+	"sync"
+)
+
+// docs contains the doc comments of configuration types and their fields,
+// keyed by package path, then by "Type" or "Type.Field".
+var docs = struct {
+	sync.RWMutex
+	m map[string]map[string]string
+}{m: map[string]map[string]string{}}
+
+// RegisterDocs registers the doc comments of the configuration types in a
+// package, keyed by "Type" or "Type.Field", so they can be used to describe
+// the configuration at runtime, such as in JSON Schemas.
+//
+// It is called by code generated by the docgen command, added to a package
+// with:
+//
+//	//go:generate go run github.com/svalevka/go/pkg/config/docgen
+func RegisterDocs(pkgPath string, comments map[string]string) {
+	docs.Lock()
+	defer docs.Unlock()
+
+	docs.m[pkgPath] = comments
+}
+
+// typeDoc returns the registered doc comment of the named type t.
+func typeDoc(t reflect.Type) string {
+	docs.RLock()
+	defer docs.RUnlock()
+
+	return docs.m[t.PkgPath()][t.Name()]
+}
+
+// fieldDoc returns the registered doc comment of the field of struct t, or
+// the doc comment of the type of the field if it is undocumented.
+func fieldDoc(t reflect.Type, f reflect.StructField) string {
+	docs.RLock()
+	doc := docs.m[t.PkgPath()][t.Name()+"."+f.Name]
+	docs.RUnlock()
+
+	if doc != "" {
+		return doc
+	}
+
+	ft := f.Type
+	for ft.Kind() == reflect.Pointer || ft.Kind() == reflect.Slice || ft.Kind() == reflect.Map {
+		ft = ft.Elem()
+	}
+
+	if ft.Name() == "" {
+		return ""
+	}
+
+	return typeDoc(ft)
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// EnvSeparator separates the prefix and the path elements of environment
+// variables that are overlaid onto configuration.
+const EnvSeparator = "__"
+
+// ApplyEnv overlays environment variables, given in the "KEY=value" form of
+// os.Environ, onto the configuration dst where the variable name begins with
+// the prefix and EnvSeparator. The remainder of the name is split by
+// EnvSeparator into a path of yaml field names, slice indexes or map keys,
+// compared without regard to case, for example:
+//
+//	NATS_JETSTREAM_STATSD__STATSD__HOST=localhost:8125
+//	NATS_JETSTREAM_STATSD__STREAMS__0__NATS__PASSWORD=changeme
+//
+// Values are converted using the same rules as YAML scalars, or decoded as a
+// YAML document where the path refers to a slice, map or struct. Map keys are
+// lowercased. All variables that cannot be applied are returned as one error.
+func ApplyEnv(prefix string, environ []string, dst any) error {
+	return applyEnv(prefix, environ, dst, nil)
+}
+
+// applyEnv implements ApplyEnv, optionally recording the origin of each value
+// that was set.
+func applyEnv(prefix string, environ []string, dst any, origins Origins) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("env: destination must be a non-nil pointer, got %T", dst)
+	}
+
+	prefix = strings.ToUpper(prefix) + EnvSeparator
+
+	errs := []error{}
+
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(strings.ToUpper(name), prefix) {
+			continue
+		}
+
+		path := strings.Split(strings.ToLower(name[len(prefix):]), EnvSeparator)
+		if slices.Contains(path, "") {
+			errs = append(errs, fmt.Errorf("env %s: empty path element", name))
+			continue
+		}
+
+		err := setPath(v, path, value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("env %s: %w", name, err))
+			continue
+		}
+
+		if origins != nil {
+			origins.set(formatPath(v.Type(), path), "env:"+name)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// EnvPrefix returns the conventional environment variable prefix for the given
+// service name, being the name up to the first dot, uppercased, with dashes
+// replaced by underscores, such that "nats-jetstream-statsd.v1" becomes
+// "NATS_JETSTREAM_STATSD".
+func EnvPrefix(serviceName string) string {
+	name, _, _ := strings.Cut(serviceName, ".")
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
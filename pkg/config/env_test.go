@@ -0,0 +1,127 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type envNATS struct {
+	Servers  []string `yaml:"servers"`
+	Password Secret   `yaml:"password"`
+}
+
+type envStream struct {
+	Name string   `yaml:"name"`
+	NATS *envNATS `yaml:"nats"`
+}
+
+type envConfig struct {
+	EnvLogs `yaml:",inline"`
+
+	StatsD struct {
+		Host    string        `yaml:"host"`
+		Timeout time.Duration `yaml:"timeout"`
+	} `yaml:"statsd"`
+
+	Streams []*envStream      `yaml:"streams"`
+	Tags    map[string]string `yaml:"tags"`
+}
+
+// EnvLogs is exported as inlined structs must be, such as common.Logs.
+type EnvLogs struct {
+	Level string `yaml:"level"`
+}
+
+func TestApplyEnv(t *testing.T) {
+	cfg := &envConfig{Streams: []*envStream{{Name: "orders"}}}
+
+	environ := []string{
+		"SVC__STATSD__HOST=localhost:8125",
+		"svc__statsd__timeout=5s",
+		"SVC__LEVEL=debug",
+		"SVC__STREAMS__0__NATS__PASSWORD=a=b",
+		"SVC__STREAMS__2__NATS__SERVERS=[nats://a:4222, nats://b:4222]",
+		"SVC__TAGS__Region=eu",
+		"OTHER__STATSD__HOST=ignored",
+		"SVC_STATSD__HOST=ignored",
+	}
+
+	err := ApplyEnv("svc", environ, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.StatsD.Host != "localhost:8125" || cfg.StatsD.Timeout != 5*time.Second {
+		t.Errorf("statsd = %+v, want the host and timeout set", cfg.StatsD)
+	}
+
+	if cfg.Level != "debug" {
+		t.Errorf("level = %q, want the inlined field set", cfg.Level)
+	}
+
+	if len(cfg.Streams) != 3 {
+		t.Fatalf("streams = %d, want grown to 3", len(cfg.Streams))
+	}
+
+	if s := cfg.Streams[0]; s.Name != "orders" || s.NATS.Password != "a=b" {
+		t.Errorf("streams[0] = %+v, want the name kept and password set", s)
+	}
+
+	// elements between the existing and new ones are allocated.
+	if cfg.Streams[1] == nil {
+		t.Error("streams[1] is nil")
+	}
+
+	if servers := cfg.Streams[2].NATS.Servers; len(servers) != 2 || servers[1] != "nats://b:4222" {
+		t.Errorf("streams[2].nats.servers = %v, want decoded from YAML", servers)
+	}
+
+	if cfg.Tags["region"] != "eu" {
+		t.Errorf("tags = %v, want the lowercased key set", cfg.Tags)
+	}
+}
+
+func TestApplyEnvErrors(t *testing.T) {
+	environ := []string{
+		"SVC__UNKNOWN=1",
+		"SVC__STATSD__TIMEOUT=soon",
+		"SVC__STREAMS__X__NAME=a",
+		"SVC____LEVEL=debug",
+		"SVC__STATSD__HOST=localhost:8125",
+	}
+
+	cfg := &envConfig{}
+
+	err := ApplyEnv("SVC", environ, cfg)
+	if err == nil {
+		t.Fatal("want an error")
+	}
+
+	for _, want := range []string{
+		`env SVC__UNKNOWN: unknown field "unknown"`,
+		"env SVC__STATSD__TIMEOUT: statsd.timeout:",
+		`env SVC__STREAMS__X__NAME: streams: invalid index "x"`,
+		"env SVC____LEVEL: empty path element",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("err = %v, want %q", err, want)
+		}
+	}
+
+	// the variables that could be applied are.
+	if cfg.StatsD.Host != "localhost:8125" {
+		t.Errorf("statsd.host = %q, want set", cfg.StatsD.Host)
+	}
+
+	err = ApplyEnv("SVC", nil, *cfg)
+	if err == nil {
+		t.Error("want an error for a non-pointer destination")
+	}
+}
+
+func TestEnvPrefix(t *testing.T) {
+	if got := EnvPrefix("nats-jetstream-statsd.v1"); got != "NATS_JETSTREAM_STATSD" {
+		t.Errorf("EnvPrefix = %s, want NATS_JETSTREAM_STATSD", got)
+	}
+}
@@ -0,0 +1,244 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Flags are command-line flags that override fields of a configuration, see
+// NewFlags.
+type Flags struct {
+	typ   reflect.Type
+	flags []*fieldFlag
+}
+
+// NewFlags defines a flag in fs for every field of the configuration type of v
+// that can be given as a single value, named by the path of yaml field names
+// separated by dots, such as -logs.debug, -listen or -statsd.host. Flags are
+// described by the doc comments of the fields, see RegisterDocs, and show the
+// value of their default tag, see SetDefaults.
+//
+// Values are converted using the same rules as YAML scalars, so durations are
+// given like 5s. Slices of scalars are given by repeating the flag, each value
+// being an item, which replace the items of the configuration rather than
+// adding to them.
+//
+// Fields of structs in slices and maps can't be given as flags, nor can
+// secrets, which would be visible to other users of the host in the arguments
+// of the process. Flags with the same name as one already defined in fs are
+// skipped.
+func NewFlags(fs *flag.FlagSet, v any) *Flags {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	f := &Flags{typ: t}
+	f.define(fs, t, nil, map[reflect.Type]bool{})
+
+	return f
+}
+
+// define defines flags for the fields of struct t at path, where seen contains
+// the struct types being walked to prevent infinite recursion.
+func (f *Flags) define(fs *flag.FlagSet, t reflect.Type, path []string, seen map[reflect.Type]bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || seen[t] {
+		return
+	}
+
+	seen[t] = true
+	defer delete(seen, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		name, inline, skip := yamlName(field)
+		if skip || field.Tag.Get("secret") == "true" {
+			continue
+		}
+
+		ft := field.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+
+		if inline {
+			f.define(fs, ft, path, seen)
+			continue
+		}
+
+		fieldPath := append(append([]string{}, path...), name)
+
+		if ft.Kind() == reflect.Struct && !isScalar(reflect.New(ft).Elem()) {
+			f.define(fs, ft, fieldPath, seen)
+			continue
+		}
+
+		ff := &fieldFlag{
+			flags: f,
+			path:  fieldPath,
+			name:  strings.Join(fieldPath, "."),
+		}
+
+		switch {
+		case ft == secretType:
+			continue
+
+		case ft.Kind() == reflect.Slice:
+			elem := ft.Elem()
+			for elem.Kind() == reflect.Pointer {
+				elem = elem.Elem()
+			}
+
+			if elem == secretType || !isScalar(reflect.New(elem).Elem()) || !isFlagKind(elem) {
+				continue
+			}
+
+			ff.slice = true
+
+		case ft.Kind() == reflect.Bool:
+			ff.bool = true
+
+		case !isFlagKind(ft):
+			continue
+		}
+
+		if fs.Lookup(ff.name) != nil {
+			continue
+		}
+
+		usage := strings.Join(strings.Fields(fieldDoc(t, field)), " ")
+		if ff.slice {
+			usage += " (repeat for each item)"
+		}
+
+		fs.Var(ff, ff.name, strings.TrimSpace(usage))
+
+		// the default is shown in the usage of the flag, but only applied
+		// by SetDefaults if no value is configured.
+		if def, ok := field.Tag.Lookup("default"); ok {
+			fs.Lookup(ff.name).DefValue = def
+		}
+
+		f.flags = append(f.flags, ff)
+	}
+}
+
+// Apply overlays the values of flags that were set onto the configuration dst.
+func (f *Flags) Apply(dst any) error {
+	return f.apply(dst, nil)
+}
+
+// apply implements Apply, optionally recording the origin of each value that
+// was set.
+func (f *Flags) apply(dst any, origins Origins) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("flags: destination must be a non-nil pointer, got %T", dst)
+	}
+
+	errs := []error{}
+
+	for _, ff := range f.flags {
+		if len(ff.values) == 0 {
+			continue
+		}
+
+		err := ff.setPath(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("flag -%s: %w", ff.name, unwrapPathError(err)))
+			continue
+		}
+
+		if origins != nil {
+			origins.set(formatPath(v.Type(), ff.path), "flag:-"+ff.name)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// fieldFlag is a flag.Value collecting the values of a flag for a field of the
+// configuration.
+type fieldFlag struct {
+	flags  *Flags
+	name   string
+	path   []string
+	slice  bool
+	bool   bool
+	values []string
+}
+
+func (ff *fieldFlag) String() string {
+	if ff == nil {
+		return ""
+	}
+
+	return strings.Join(ff.values, ",")
+}
+
+// Set checks the value can be applied to the field, so invalid values are
+// reported when flags are parsed.
+func (ff *fieldFlag) Set(value string) error {
+	if !ff.slice {
+		ff.values = nil
+	}
+
+	ff.values = append(ff.values, value)
+
+	return unwrapPathError(ff.setPath(reflect.New(ff.flags.typ)))
+}
+
+func (ff *fieldFlag) IsBoolFlag() bool {
+	return ff.bool
+}
+
+// setPath sets the field of the configuration v to the values of the flag.
+func (ff *fieldFlag) setPath(v reflect.Value) error {
+	if !ff.slice {
+		return setPath(v, ff.path, ff.values[0])
+	}
+
+	err := setPath(v, ff.path, "[]")
+	if err != nil {
+		return err
+	}
+
+	for i, value := range ff.values {
+		err = setPath(v, append(append([]string{}, ff.path...), strconv.Itoa(i)), value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isFlagKind returns whether a value of type t can be given as a single flag
+// value.
+func isFlagKind(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Interface, reflect.Chan, reflect.Func:
+		return isUnmarshaler(reflect.New(t).Elem())
+	}
+
+	return true
+}
+
+// unwrapPathError returns the underlying error of a pathError, as the path is
+// implied by the name of the flag.
+func unwrapPathError(err error) error {
+	if pe, ok := err.(*pathError); ok {
+		return pe.err
+	}
+
+	return err
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type flagsStatsD struct {
+	Host    string        `yaml:"host" default:"localhost:8125"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type flagsStream struct {
+	Name string `yaml:"name"`
+}
+
+type flagsConfig struct {
+	EnvLogs `yaml:",inline"`
+
+	Debug    bool              `yaml:"debug"`
+	Listen   []string          `yaml:"listen" default:"[localhost:8080]"`
+	StatsD   *flagsStatsD      `yaml:"statsd"`
+	Password Secret            `yaml:"password"`
+	Key      string            `yaml:"key" secret:"true"`
+	Streams  []flagsStream     `yaml:"streams"`
+	Tags     map[string]string `yaml:"tags"`
+	Next     *flagsConfig      `yaml:"next"`
+}
+
+// newFlagSet returns a FlagSet with flags for flagsConfig.
+func newFlagSet() (*flag.FlagSet, *Flags) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	return fs, NewFlags(fs, &flagsConfig{})
+}
+
+func TestNewFlags(t *testing.T) {
+	RegisterDocs(reflect.TypeOf(flagsStatsD{}).PkgPath(), map[string]string{
+		"flagsStatsD.Host": "Host is the address of the StatsD\nserver.",
+	})
+
+	fs, _ := newFlagSet()
+
+	names := []string{}
+	fs.VisitAll(func(f *flag.Flag) {
+		names = append(names, f.Name)
+	})
+
+	// secrets, and fields of structs in slices and maps, aren't flags.
+	want := []string{"debug", "level", "listen", "statsd.host", "statsd.timeout"}
+	if !slices.Equal(names, want) {
+		t.Errorf("flags = %v, want %v", names, want)
+	}
+
+	host := fs.Lookup("statsd.host")
+	if host.Usage != "Host is the address of the StatsD server." || host.DefValue != "localhost:8125" {
+		t.Errorf("statsd.host = %+v, want documented with its default", host)
+	}
+
+	if usage := fs.Lookup("listen").Usage; !strings.HasSuffix(usage, "(repeat for each item)") {
+		t.Errorf("listen usage = %q, want repeatable", usage)
+	}
+}
+
+func TestFlagsApply(t *testing.T) {
+	fs, flags := newFlagSet()
+
+	err := fs.Parse([]string{"-debug", "-level=warn", "-listen", "a:1", "-listen", "b:2", "-statsd.timeout", "5s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &flagsConfig{Listen: []string{"c:3", "d:4", "e:5"}, StatsD: &flagsStatsD{Host: "statsd:8125"}}
+
+	err = flags.Apply(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !cfg.Debug || cfg.Level != "warn" {
+		t.Errorf("debug = %v, level = %q, want set", cfg.Debug, cfg.Level)
+	}
+
+	// the items of slices are replaced.
+	if !slices.Equal(cfg.Listen, []string{"a:1", "b:2"}) {
+		t.Errorf("listen = %v, want replaced by the flags", cfg.Listen)
+	}
+
+	if cfg.StatsD.Host != "statsd:8125" || cfg.StatsD.Timeout != 5*time.Second {
+		t.Errorf("statsd = %+v, want the host kept and timeout set", cfg.StatsD)
+	}
+}
+
+func TestFlagsInvalid(t *testing.T) {
+	fs, _ := newFlagSet()
+
+	err := fs.Parse([]string{"-statsd.timeout", "soon"})
+	if err == nil || !strings.Contains(err.Error(), `invalid value "soon" for flag -statsd.timeout`) {
+		t.Errorf("err = %v, want the invalid duration reported when parsed", err)
+	}
+}
+
+func TestLoaderFlags(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestFile(t, dir, "config.yml", "level: info\nstatsd:\n  host: a:1\n")
+
+	fs, flags := newFlagSet()
+
+	err := fs.Parse([]string{"-statsd.host", "b:2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &flagsConfig{}
+
+	// flags override environment variables, which override files.
+	origins, err := (&Loader{
+		EnvPrefix: "TEST",
+		Environ:   func() []string { return []string{"TEST__STATSD__HOST=c:3", "TEST__LEVEL=debug"} },
+		Flags:     flags,
+	}).FromFiles([]string{path}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.StatsD.Host != "b:2" || cfg.Level != "debug" {
+		t.Errorf("config = %+v, want the flag and environment applied", cfg)
+	}
+
+	if origins["statsd.host"] != "flag:-statsd.host" || origins["level"] != "env:TEST__LEVEL" {
+		t.Errorf("origins = %v, want the flag and environment variable", origins)
+	}
+
+	if !slices.Equal(cfg.Listen, []string{"localhost:8080"}) {
+		t.Errorf("listen = %v, want the default without the flag", cfg.Listen)
+	}
+}
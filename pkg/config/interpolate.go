@@ -0,0 +1,161 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Resolver returns the value referenced by ref, the part of a reference after
+// the scheme, for example the path of the ${file:/run/secrets/nats} reference.
+type Resolver func(ref string) (string, error)
+
+// resolve returns the value of the reference given in the scheme:ref form,
+// using resolvers configured on the Loader before the builtin env, file and
+// cred schemes.
+func (l *Loader) resolve(reference string) (string, error) {
+	scheme, ref, ok := strings.Cut(reference, ":")
+	if !ok {
+		return "", fmt.Errorf("reference %q has no scheme", reference)
+	}
+
+	if r, ok := l.Resolvers[scheme]; ok {
+		return r(ref)
+	}
+
+	switch scheme {
+	case "env":
+		return l.resolveEnv(ref)
+
+	case "file":
+		return resolveFile(ref)
+
+	case "cred":
+		return resolveCred(ref)
+	}
+
+	return "", fmt.Errorf("reference %q has unknown scheme %q", reference, scheme)
+}
+
+// resolveEnv returns the value of the environment variable name, which must be
+// set.
+func (l *Loader) resolveEnv(name string) (string, error) {
+	for _, kv := range l.environ() {
+		if k, v, _ := strings.Cut(kv, "="); k == name {
+			return v, nil
+		}
+	}
+
+	return "", fmt.Errorf("environment variable %q is not set", name)
+}
+
+// resolveFile returns the contents of the file at path, without trailing line
+// endings.
+func resolveFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// resolveCred returns the contents of the systemd credential with the given
+// name, as passed to the service with LoadCredential= or SetCredential=.
+func resolveCred(name string) (string, error) {
+	dir := os.Getenv("CREDENTIALS_DIRECTORY")
+	if dir == "" {
+		return "", errors.New("CREDENTIALS_DIRECTORY is not set, is the service running under systemd with credentials?")
+	}
+
+	if name == "" || strings.ContainsRune(name, '/') || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid credential name %q", name)
+	}
+
+	return resolveFile(filepath.Join(dir, name))
+}
+
+// interpolate replaces all ${scheme:ref} references in s with their resolved
+// values, where $${ is an escaped, literal ${.
+func (l *Loader) interpolate(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	b := strings.Builder{}
+
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+
+		if start > 0 && s[start-1] == '$' {
+			b.WriteString(s[:start])
+			b.WriteString("{")
+			s = s[start+2:]
+			continue
+		}
+
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated reference in %q", s[start:])
+		}
+
+		value, err := l.resolve(s[start+2 : start+end])
+		if err != nil {
+			return "", err
+		}
+
+		b.WriteString(s[:start])
+		b.WriteString(value)
+		s = s[start+end+1:]
+	}
+}
+
+// interpolateNode interpolates the values of all scalar nodes in the document
+// in place, returning all errors encountered with their position in file.
+func (l *Loader) interpolateNode(file string, n *yaml.Node) error {
+	errs := []error{}
+
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		if n.Kind == yaml.ScalarNode {
+			value, err := l.interpolate(n.Value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s:%d:%d: %w", file, n.Line, n.Column, err))
+				return
+			}
+
+			if value != n.Value {
+				n.Value = value
+
+				// re-resolve the implicit tag of plain scalars, so a
+				// reference resolving to a number can be decoded as one.
+				if n.Style&yaml.TaggedStyle == 0 {
+					n.Tag = ""
+				}
+			}
+
+			return
+		}
+
+		for i, c := range n.Content {
+			// only interpolate the values of mappings, never the keys.
+			if n.Kind == yaml.MappingNode && i%2 == 0 {
+				continue
+			}
+
+			walk(c)
+		}
+	}
+
+	walk(n)
+
+	return errors.Join(errs...)
+}
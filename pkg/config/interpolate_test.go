@@ -0,0 +1,140 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestFile writes contents to the file name in dir, returning its path.
+func writeTestFile(t *testing.T, dir, name, contents string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+
+	err := os.WriteFile(path, []byte(contents), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+type interpolateConfig struct {
+	Password Secret            `yaml:"password"`
+	Token    Secret            `yaml:"token"`
+	Port     int               `yaml:"port"`
+	Literal  string            `yaml:"literal"`
+	Custom   string            `yaml:"custom"`
+	Tags     map[string]string `yaml:"tags"`
+}
+
+func TestLoaderInterpolates(t *testing.T) {
+	dir := t.TempDir()
+
+	secret := writeTestFile(t, dir, "secret", "hunter2\n")
+	t.Setenv("CREDENTIALS_DIRECTORY", dir)
+
+	path := writeTestFile(t, dir, "config.yml", `
+password: ${file:`+secret+`}
+token: prefix-${cred:secret}
+port: ${env:PORT}
+literal: $${env:PORT}
+custom: ${vault:nats/password}
+tags:
+  ${env:PORT}: key
+`)
+
+	cfg := &interpolateConfig{}
+
+	err := (&Loader{
+		Environ: func() []string { return []string{"PORT=4222"} },
+		Resolvers: map[string]Resolver{
+			"vault": func(ref string) (string, error) { return "vault:" + ref, nil },
+		},
+	}).FromFile(path, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := interpolateConfig{
+		Password: "hunter2",
+		Token:    "prefix-hunter2",
+		Port:     4222,
+		Literal:  "${env:PORT}",
+		Custom:   "vault:nats/password",
+	}
+
+	if cfg.Password != want.Password || cfg.Token != want.Token || cfg.Port != want.Port ||
+		cfg.Literal != want.Literal || cfg.Custom != want.Custom {
+		t.Errorf("config = %+v, want %+v", cfg, want)
+	}
+
+	// keys are never interpolated.
+	if cfg.Tags["${env:PORT}"] != "key" {
+		t.Errorf("tags = %v, want the key kept", cfg.Tags)
+	}
+}
+
+func TestLoaderInterpolatesEnv(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestFile(t, dir, "config.yml", "port: 1\n")
+
+	cfg := &interpolateConfig{}
+
+	err := (&Loader{
+		EnvPrefix: "TEST",
+		Environ:   func() []string { return []string{"TEST__PASSWORD=${env:PASSWORD}", "PASSWORD=hunter2"} },
+	}).FromFile(path, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Password != "hunter2" {
+		t.Errorf("password = %q, want interpolated from the environment", cfg.Password)
+	}
+}
+
+func TestLoaderInterpolateErrors(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", dir)
+
+	path := writeTestFile(t, dir, "config.yml", `
+password: ${env:MISSING}
+token: ${file:`+filepath.Join(dir, "missing")+`}
+literal: ${unknown:x}
+custom: ${cred:../secret}
+tags:
+  a: ${noscheme}
+  b: ${env:PORT
+`)
+
+	err := (&Loader{Environ: func() []string { return nil }}).FromFile(path, &interpolateConfig{})
+	if err == nil {
+		t.Fatal("want an error")
+	}
+
+	// every reference that can't be resolved is reported with its position.
+	for _, want := range []string{
+		path + `:2:11: environment variable "MISSING" is not set`,
+		path + ":3:8: open ",
+		path + `:4:10: reference "unknown:x" has unknown scheme "unknown"`,
+		path + `:5:9: invalid credential name "../secret"`,
+		path + `:7:6: reference "noscheme" has no scheme`,
+		path + `:8:6: unterminated reference in "${env:PORT"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("err = %v, want %q", err, want)
+		}
+	}
+}
+
+func TestResolveCredWithoutDirectory(t *testing.T) {
+	t.Setenv("CREDENTIALS_DIRECTORY", "")
+
+	_, err := resolveCred("nats")
+	if err == nil || !strings.Contains(err.Error(), "CREDENTIALS_DIRECTORY is not set") {
+		t.Errorf("err = %v, want CREDENTIALS_DIRECTORY required", err)
+	}
+}
@@ -0,0 +1,230 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SliceStrategy controls how a sequence in a later configuration file is
+// merged with the same sequence in an earlier file.
+type SliceStrategy int
+
+const (
+	// SliceReplace replaces the earlier sequence with the later one.
+	SliceReplace SliceStrategy = iota
+
+	// SliceAppend appends the items of the later sequence to the earlier
+	// one.
+	SliceAppend
+)
+
+// YAML tags that override the SliceStrategy of the Loader for a single
+// sequence, for example:
+//
+//	listen: !append
+//	- localhost:8081
+const (
+	TagAppend  = "!append"
+	TagReplace = "!replace"
+)
+
+// Origins maps the path of each value in the configuration, such as
+// "streams[0].nats.servers[1]", to where it was loaded from, either the
+// "file:line" of a configuration file, "env:NAME" of an environment variable
+// or "flag:-name" of a command-line flag.
+type Origins map[string]string
+
+// set records the origin of the value at path, which replaces any values
+// nested below it.
+func (o Origins) set(path, origin string) {
+	for k := range o {
+		if strings.HasPrefix(k, path+".") || strings.HasPrefix(k, path+"[") {
+			delete(o, k)
+		}
+	}
+
+	o[path] = origin
+}
+
+// String returns the Origins as sorted lines of the path and its origin.
+func (o Origins) String() string {
+	paths := make([]string, 0, len(o))
+	for path := range o {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	b := strings.Builder{}
+	for _, path := range paths {
+		b.WriteString(path + "\t" + o[path] + "\n")
+	}
+
+	return b.String()
+}
+
+// expandPaths returns the configuration files found at the given paths in
+// order, where directories are expanded into the JSON and YAML files they
+// directly contain, sorted by name, as is conventional for conf.d
+// directories.
+func expandPaths(paths []string) ([]string, error) {
+	files := []string{}
+
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("open: %w", err)
+		}
+
+		if !fi.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("open: %w", err)
+		}
+
+		// entries are already sorted by file name.
+		for _, entry := range entries {
+			switch filepath.Ext(entry.Name()) {
+			case ".yml", ".yaml", ".json":
+				if !entry.IsDir() {
+					files = append(files, filepath.Join(path, entry.Name()))
+				}
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// merge deep merges the node src over dst, returning the result: mappings are
+// merged by key, sequences are merged by strategy or the TagAppend and
+// TagReplace tags, and everything else is replaced by src.
+func merge(dst, src *yaml.Node, strategy SliceStrategy) *yaml.Node {
+	if dst == nil {
+		return clearTags(src)
+	}
+
+	if dst.Kind == yaml.DocumentNode && src.Kind == yaml.DocumentNode {
+		if len(dst.Content) == 0 || len(src.Content) == 0 {
+			return merge(nil, src, strategy)
+		}
+
+		dst.Content[0] = merge(dst.Content[0], src.Content[0], strategy)
+		return dst
+	}
+
+	if dst.Kind == yaml.MappingNode && src.Kind == yaml.MappingNode {
+	keys:
+		for i := 0; i+1 < len(src.Content); i += 2 {
+			key, value := src.Content[i], src.Content[i+1]
+
+			for j := 0; j+1 < len(dst.Content); j += 2 {
+				if dst.Content[j].Value == key.Value {
+					dst.Content[j+1] = merge(dst.Content[j+1], value, strategy)
+					continue keys
+				}
+			}
+
+			dst.Content = append(dst.Content, key, clearTags(value))
+		}
+
+		return dst
+	}
+
+	if dst.Kind == yaml.SequenceNode && src.Kind == yaml.SequenceNode {
+		switch src.Tag {
+		case TagAppend:
+			strategy = SliceAppend
+		case TagReplace:
+			strategy = SliceReplace
+		}
+
+		if strategy == SliceAppend {
+			for _, item := range src.Content {
+				dst.Content = append(dst.Content, clearTags(item))
+			}
+
+			return dst
+		}
+	}
+
+	return clearTags(src)
+}
+
+// clearTags removes the TagAppend and TagReplace tags from n and its children,
+// which are only meaningful while merging.
+func clearTags(n *yaml.Node) *yaml.Node {
+	if n.Tag == TagAppend || n.Tag == TagReplace {
+		n.Tag = "!!seq"
+		n.Style &^= yaml.TaggedStyle
+	}
+
+	for _, c := range n.Content {
+		clearTags(c)
+	}
+
+	return n
+}
+
+// nodeOrigins records the origin of every scalar, and every empty sequence or
+// mapping, in the document n, using the files each node was read from.
+func nodeOrigins(n *yaml.Node, files map[*yaml.Node]string, origins Origins) {
+	var walk func(path string, n *yaml.Node)
+	walk = func(path string, n *yaml.Node) {
+		switch n.Kind {
+		case yaml.DocumentNode:
+			for _, c := range n.Content {
+				walk(path, c)
+			}
+			return
+
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				key := n.Content[i].Value
+				if path != "" {
+					key = path + "." + key
+				}
+
+				walk(key, n.Content[i+1])
+			}
+
+			if len(n.Content) > 0 {
+				return
+			}
+
+		case yaml.SequenceNode:
+			for i, c := range n.Content {
+				walk(path+"["+strconv.Itoa(i)+"]", c)
+			}
+
+			if len(n.Content) > 0 {
+				return
+			}
+		}
+
+		if path != "" {
+			origins[path] = files[n] + ":" + strconv.Itoa(n.Line)
+		}
+	}
+
+	walk("", n)
+}
+
+// recordFile records file as the origin of n and all of its children.
+func recordFile(n *yaml.Node, file string, files map[*yaml.Node]string) {
+	files[n] = file
+
+	for _, c := range n.Content {
+		recordFile(c, file, files)
+	}
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+type mergeServer struct {
+	Addr    string `yaml:"addr"`
+	Retries int    `yaml:"retries"`
+}
+
+type mergeConfig struct {
+	Server  mergeServer       `yaml:"server"`
+	Listen  []string          `yaml:"listen"`
+	Hosts   []string          `yaml:"hosts"`
+	Tags    map[string]string `yaml:"tags"`
+	Servers []mergeServer     `yaml:"servers"`
+}
+
+func TestLoaderMergesFiles(t *testing.T) {
+	dir := t.TempDir()
+	confd := filepath.Join(dir, "conf.d")
+
+	err := os.Mkdir(confd, 0o700)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := writeTestFile(t, dir, "config.yml", `
+server:
+  addr: localhost:8080
+  retries: 3
+listen: [a, b]
+hosts: [a]
+tags: {env: prod, region: eu}
+servers: [{addr: a}]
+`)
+
+	writeTestFile(t, confd, "20-hosts.yml", "hosts: !append [c]\n")
+	writeTestFile(t, confd, "10-server.json", `{"server": {"retries": 5}, "tags": {"region": "us"}}`)
+	writeTestFile(t, confd, "README.md", "not configuration")
+	writeTestFile(t, dir, "local.yml", "listen: [c]\nservers: [{retries: 1}]\n")
+
+	cfg := &mergeConfig{}
+
+	origins, err := (&Loader{}).FromFiles([]string{base, confd, filepath.Join(dir, "local.yml")}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Server != (mergeServer{Addr: "localhost:8080", Retries: 5}) {
+		t.Errorf("server = %+v, want merged by key", cfg.Server)
+	}
+
+	if !slices.Equal(cfg.Listen, []string{"c"}) {
+		t.Errorf("listen = %v, want replaced", cfg.Listen)
+	}
+
+	if !slices.Equal(cfg.Hosts, []string{"a", "c"}) {
+		t.Errorf("hosts = %v, want appended by the tag", cfg.Hosts)
+	}
+
+	if cfg.Tags["env"] != "prod" || cfg.Tags["region"] != "us" {
+		t.Errorf("tags = %v, want merged by key", cfg.Tags)
+	}
+
+	// items of sequences are replaced, not merged.
+	if len(cfg.Servers) != 1 || cfg.Servers[0] != (mergeServer{Retries: 1}) {
+		t.Errorf("servers = %+v, want replaced", cfg.Servers)
+	}
+
+	want := map[string]string{
+		"server.addr":        base + ":3",
+		"server.retries":     filepath.Join(confd, "10-server.json") + ":1",
+		"hosts[0]":           base + ":6",
+		"hosts[1]":           filepath.Join(confd, "20-hosts.yml") + ":1",
+		"listen[0]":          filepath.Join(dir, "local.yml") + ":1",
+		"servers[0].retries": filepath.Join(dir, "local.yml") + ":2",
+	}
+
+	for path, origin := range want {
+		if origins[path] != origin {
+			t.Errorf("origin of %s = %q, want %q", path, origins[path], origin)
+		}
+	}
+
+	if _, ok := origins["listen[1]"]; ok {
+		t.Error("want no origin for the replaced listen[1]")
+	}
+}
+
+func TestLoaderSliceAppend(t *testing.T) {
+	dir := t.TempDir()
+
+	base := writeTestFile(t, dir, "config.yml", "listen: [a]\nhosts: [a]\n")
+	local := writeTestFile(t, dir, "local.yml", "listen: [b]\nhosts: !replace [b]\n")
+
+	cfg := &mergeConfig{}
+
+	_, err := (&Loader{Slices: SliceAppend}).FromFiles([]string{base, local}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(cfg.Listen, []string{"a", "b"}) {
+		t.Errorf("listen = %v, want appended", cfg.Listen)
+	}
+
+	if !slices.Equal(cfg.Hosts, []string{"b"}) {
+		t.Errorf("hosts = %v, want replaced by the tag", cfg.Hosts)
+	}
+}
+
+func TestLoaderEmptyFile(t *testing.T) {
+	dir := t.TempDir()
+
+	base := writeTestFile(t, dir, "config.yml", "listen: [a]\n")
+	empty := writeTestFile(t, dir, "empty.yml", "")
+
+	cfg := &mergeConfig{}
+
+	_, err := (&Loader{}).FromFiles([]string{base, empty}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(cfg.Listen, []string{"a"}) {
+		t.Errorf("listen = %v, want kept", cfg.Listen)
+	}
+
+	_, err = (&Loader{}).FromFiles([]string{filepath.Join(dir, "missing.yml")}, cfg)
+	if err == nil {
+		t.Error("want an error for a missing file")
+	}
+}
+
+func TestOriginsString(t *testing.T) {
+	o := Origins{}
+	o.set("servers[0].addr", "config.yml:2")
+	o.set("servers[0].retries", "config.yml:3")
+	o.set("listen", "flag:-listen")
+
+	// a value replaces those nested below it.
+	o.set("servers", "env:SVC__SERVERS")
+
+	want := "listen\tflag:-listen\nservers\tenv:SVC__SERVERS\n"
+	if got := o.String(); got != want {
+		t.Errorf("origins = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,314 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// VersionKey is the reserved top-level key holding the version of the format
+// of a configuration file, where a file without it is version 1.
+const VersionKey = "version"
+
+// Migration upgrades a configuration document by a single version, modifying
+// the mapping at the root of the document in place.
+//
+// As files are migrated individually before they are merged, a Migration must
+// tolerate documents that only contain part of the configuration, such as the
+// files of a conf.d directory.
+type Migration func(root *yaml.Node) error
+
+// Migrator is implemented by configuration types whose file format has changed
+// incompatibly, returning the Migrations upgrading each version to the next,
+// where the first upgrades version 1 to 2. The current version is therefore
+// one more than the number of Migrations, and Migrations must only be appended.
+type Migrator interface {
+	ConfigMigrations() []Migration
+}
+
+var migratorType = reflect.TypeOf((*Migrator)(nil)).Elem()
+
+// Version returns the current version of the file format of the configuration
+// type of v, see Migrator.
+func Version(v any) int {
+	return len(migrations(reflect.TypeOf(v))) + 1
+}
+
+// migrations returns the Migrations of the configuration type t.
+func migrations(t reflect.Type) []Migration {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == nil || !reflect.PointerTo(t).Implements(migratorType) {
+		return nil
+	}
+
+	return reflect.New(t).Interface().(Migrator).ConfigMigrations()
+}
+
+// Migrate upgrades the YAML document doc to the current version of the
+// configuration type of v, returning the version it was upgraded from. The
+// version key of the document is set to the current version if it was
+// upgraded.
+func Migrate(doc *yaml.Node, v any) (int, error) {
+	root := doc
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+
+	if root.Kind != yaml.MappingNode {
+		// there's nothing to migrate, and the document is reported as
+		// invalid when it is decoded.
+		return 1, nil
+	}
+
+	migrations := migrations(reflect.TypeOf(v))
+	current := len(migrations) + 1
+
+	from, err := documentVersion(root)
+	if err != nil {
+		return 0, err
+	}
+
+	if from > current {
+		return 0, fmt.Errorf("%s: %d is newer than the supported version %d", VersionKey, from, current)
+	}
+
+	for i := from; i < current; i++ {
+		err = migrations[i-1](root)
+		if err != nil {
+			return 0, fmt.Errorf("version %d to %d: %w", i, i+1, err)
+		}
+	}
+
+	if from < current {
+		setVersion(root, current)
+	}
+
+	return from, nil
+}
+
+// documentVersion returns the version of the document with the mapping root.
+func documentVersion(root *yaml.Node) (int, error) {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != VersionKey {
+			continue
+		}
+
+		value := root.Content[i+1]
+
+		version, err := strconv.Atoi(value.Value)
+		if err != nil || value.Kind != yaml.ScalarNode || version < 1 {
+			return 0, fmt.Errorf("%s: expected a positive integer, got %s", VersionKey, kindName(value))
+		}
+
+		return version, nil
+	}
+
+	return 1, nil
+}
+
+// setVersion sets the version of the document with the mapping root, adding
+// the version key to the top of the document if it's missing.
+func setVersion(root *yaml.Node, version int) {
+	value := strconv.Itoa(version)
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == VersionKey {
+			root.Content[i+1].SetString(value)
+			root.Content[i+1].Tag = "!!int"
+			return
+		}
+	}
+
+	root.Content = append([]*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: VersionKey},
+		{Kind: yaml.ScalarNode, Tag: "!!int", Value: value},
+	}, root.Content...)
+}
+
+// removeVersion removes the version key from the YAML document doc, as it
+// isn't a field of the configuration type.
+func removeVersion(doc *yaml.Node) {
+	root := doc
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+
+	if root.Kind != yaml.MappingNode {
+		return
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == VersionKey {
+			root.Content = append(root.Content[:i], root.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// versionNode adds the current version of the configuration type of v to the
+// top of the mapping n, if the type has ever been migrated.
+func versionNode(v any, n *yaml.Node) {
+	if version := Version(v); version > 1 && n.Kind == yaml.MappingNode {
+		setVersion(n, version)
+	}
+}
+
+// Migrated describes a configuration file upgraded by MigrateFiles.
+type Migrated struct {
+	// Path is the path of the configuration file.
+	Path string
+
+	// From and To are the versions the file was upgraded from and to, which
+	// are equal if the file was already current.
+	From int
+	To   int
+
+	// Backup is the path of a copy of the original file, or empty if the file
+	// was already current.
+	Backup string
+}
+
+// MigrateFiles upgrades each configuration file at paths to the current version
+// of the configuration type of v, expanding directories like FromFiles. The
+// original of each upgraded file is kept alongside it with a suffix of the
+// version it was upgraded from, such as config.yml.v1.bak.
+//
+// Files are rewritten with comments preserved, but references such as
+// ${env:NAME} are kept rather than interpolated, and YAML formatting may
+// change. JSON files are rewritten as JSON, without their original key order.
+func MigrateFiles(paths []string, v any) ([]*Migrated, error) {
+	files, err := expandPaths(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	migrated := []*Migrated{}
+
+	for _, path := range files {
+		m, err := migrateFile(path, v)
+		if err != nil {
+			return migrated, fmt.Errorf("migrate: %s: %w", path, err)
+		}
+
+		migrated = append(migrated, m)
+	}
+
+	return migrated, nil
+}
+
+// migrateFile upgrades the configuration file at path, see MigrateFiles.
+func migrateFile(path string, v any) (*Migrated, error) {
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Migrated{Path: path, From: Version(v), To: Version(v)}
+
+	doc := &yaml.Node{}
+
+	err = yaml.NewDecoder(bytes.NewReader(original)).Decode(doc)
+	if errors.Is(err, io.EOF) {
+		return m, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("yaml: %w", err)
+	}
+
+	m.From, err = Migrate(doc, v)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.From == m.To {
+		return m, nil
+	}
+
+	var upgraded []byte
+	if filepath.Ext(path) == ".json" {
+		var data any
+
+		err = doc.Decode(&data)
+		if err == nil {
+			upgraded, err = json.MarshalIndent(data, "", "  ")
+			upgraded = append(upgraded, '\n')
+		}
+	} else {
+		b := &bytes.Buffer{}
+		if bytes.HasPrefix(original, []byte("---")) {
+			b.WriteString("---\n")
+		}
+
+		enc := yaml.NewEncoder(b)
+		enc.SetIndent(2)
+
+		err = enc.Encode(doc)
+		if err == nil {
+			err = enc.Close()
+		}
+
+		upgraded = b.Bytes()
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("encode: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// the backup is never overwritten, so the original of a file that was
+	// already migrated can't be lost.
+	m.Backup = path + ".v" + strconv.Itoa(m.From) + ".bak"
+
+	err = writeFile(m.Backup, original, info.Mode().Perm(), os.O_EXCL)
+	if err != nil {
+		return nil, fmt.Errorf("backup: %w", err)
+	}
+
+	// the upgraded file is renamed into place, so the file is never left
+	// partially written.
+	tmp := path + ".tmp"
+
+	err = writeFile(tmp, upgraded, info.Mode().Perm(), os.O_TRUNC)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// writeFile creates the file at path with the given contents and permissions,
+// where flag is either os.O_EXCL or os.O_TRUNC.
+func writeFile(path string, data []byte, perm os.FileMode, flag int) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|flag, perm)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(data)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,191 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+type migrateConfig struct {
+	Title string   `yaml:"title"`
+	Hosts []string `yaml:"hosts"`
+}
+
+func (c *migrateConfig) ConfigMigrations() []Migration {
+	return []Migration{
+		renameKey("name", "title"),
+		hostsList,
+	}
+}
+
+// renameKey returns a Migration renaming the top-level key from to to.
+func renameKey(from, to string) Migration {
+	return func(root *yaml.Node) error {
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if root.Content[i].Value == from {
+				root.Content[i].Value = to
+			}
+		}
+
+		return nil
+	}
+}
+
+// hostsList upgrades hosts from a comma separated string to a list.
+func hostsList(root *yaml.Node) error {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "hosts" || root.Content[i+1].Kind != yaml.ScalarNode {
+			continue
+		}
+
+		list := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, host := range strings.Split(root.Content[i+1].Value, ",") {
+			list.Content = append(list.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: host})
+		}
+
+		root.Content[i+1] = list
+	}
+
+	return nil
+}
+
+func TestLoaderMigrates(t *testing.T) {
+	dir := t.TempDir()
+
+	v1 := writeTestFile(t, dir, "config.yml", "name: a\nhosts: a,b\n")
+	v2 := writeTestFile(t, dir, "local.yml", "version: 2\nhosts: c\n")
+	v3 := writeTestFile(t, dir, "override.yml", "version: 3\ntitle: b\n")
+
+	cfg := &migrateConfig{}
+
+	err := (&Loader{}).FromFile(v1, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Title != "a" || !slices.Equal(cfg.Hosts, []string{"a", "b"}) {
+		t.Errorf("config = %+v, want version 1 upgraded", cfg)
+	}
+
+	// files are migrated individually before they are merged.
+	cfg = &migrateConfig{}
+
+	_, err = (&Loader{}).FromFiles([]string{v1, v2, v3}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Title != "b" || !slices.Equal(cfg.Hosts, []string{"c"}) {
+		t.Errorf("config = %+v, want every version upgraded and merged", cfg)
+	}
+
+	if Version(cfg) != 3 {
+		t.Errorf("version = %d, want 3", Version(cfg))
+	}
+}
+
+func TestMigrateErrors(t *testing.T) {
+	for _, tt := range []struct {
+		doc  string
+		want string
+	}{
+		{"version: 4\n", "version: 4 is newer than the supported version 3"},
+		{"version: 0\n", `version: expected a positive integer, got scalar "0"`},
+		{"version: [1]\n", "version: expected a positive integer, got a sequence"},
+	} {
+		doc := &yaml.Node{}
+
+		err := yaml.Unmarshal([]byte(tt.doc), doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = Migrate(doc, &migrateConfig{})
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Migrate(%q) = %v, want %s", tt.doc, err, tt.want)
+		}
+	}
+}
+
+func TestMigrateFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	yml := writeTestFile(t, dir, "config.yml", "---\n# the title\nname: a # inline\nhosts: ${env:HOSTS}\n")
+	js := writeTestFile(t, dir, "config.json", `{"hosts": "a,b"}`)
+	current := writeTestFile(t, dir, "current.yml", "version: 3\ntitle: a\n")
+
+	migrated, err := MigrateFiles([]string{yml, js, current}, &migrateConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(migrated) != 3 {
+		t.Fatalf("migrated = %d files, want 3", len(migrated))
+	}
+
+	if m := migrated[0]; m.From != 1 || m.To != 3 || m.Backup != yml+".v1.bak" {
+		t.Errorf("migrated[0] = %+v, want version 1 to 3 with a backup", m)
+	}
+
+	if m := migrated[2]; m.From != 3 || m.Backup != "" {
+		t.Errorf("migrated[2] = %+v, want already current", m)
+	}
+
+	b, err := os.ReadFile(yml)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// comments and references are kept.
+	want := "---\nversion: 3\n# the title\ntitle: a # inline\nhosts:\n  - ${env:HOSTS}\n"
+	if string(b) != want {
+		t.Errorf("config.yml = %q, want %q", b, want)
+	}
+
+	b, err = os.ReadFile(yml + ".v1.bak")
+	if err != nil || !strings.Contains(string(b), "name: a") {
+		t.Errorf("backup = %q, %v, want the original", b, err)
+	}
+
+	b, err = os.ReadFile(js)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var data map[string]any
+
+	err = json.Unmarshal(b, &data)
+	if err != nil {
+		t.Fatalf("config.json isn't JSON: %v: %s", err, b)
+	}
+
+	if data["version"] != 3.0 || len(data["hosts"].([]any)) != 2 {
+		t.Errorf("config.json = %s, want upgraded", b)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "current.yml.v3.bak")); err == nil {
+		t.Error("want no backup of a current file")
+	}
+}
+
+func TestMigrateFilesKeepsBackup(t *testing.T) {
+	dir := t.TempDir()
+
+	path := writeTestFile(t, dir, "config.yml", "name: a\n")
+	writeTestFile(t, dir, "config.yml.v1.bak", "name: original\n")
+
+	_, err := MigrateFiles([]string{path}, &migrateConfig{})
+	if err == nil || !strings.Contains(err.Error(), "backup:") {
+		t.Fatalf("err = %v, want the existing backup kept", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil || string(b) != "name: a\n" {
+		t.Errorf("config.yml = %q, %v, want unchanged", b, err)
+	}
+}
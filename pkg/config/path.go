@@ -0,0 +1,364 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlName returns the name of a struct field as it would be marshaled by the
+// yaml package, whether the field is inlined into its parent, and whether it
+// is skipped entirely.
+func yamlName(f reflect.StructField) (name string, inline bool, skip bool) {
+	if !f.IsExported() {
+		return "", false, true
+	}
+
+	tag := f.Tag.Get("yaml")
+	if tag == "-" {
+		return "", false, true
+	}
+
+	name, opts, _ := strings.Cut(tag, ",")
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == "inline" {
+			return "", true, false
+		}
+	}
+
+	if name == "" {
+		name = strings.ToLower(f.Name)
+	}
+
+	return name, false, false
+}
+
+// fieldByName returns the struct field of v with the given yaml name, searching
+// inlined structs, or false if no field exists. Names are compared without
+// regard to case.
+func fieldByName(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		fn, inline, skip := yamlName(t.Field(i))
+		if skip {
+			continue
+		}
+
+		fv := v.Field(i)
+
+		if inline {
+			if fv.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					fv.Set(reflect.New(fv.Type().Elem()))
+				}
+
+				fv = fv.Elem()
+			}
+
+			if fv.Kind() == reflect.Struct {
+				if found, ok := fieldByName(fv, name); ok {
+					return found, true
+				}
+			}
+
+			continue
+		}
+
+		if strings.EqualFold(fn, name) {
+			return fv, true
+		}
+	}
+
+	return reflect.Value{}, false
+}
+
+// setPath walks v along path, where each element is either the yaml name of a
+// struct field, the index of a slice element or the key of a map, and sets the
+// value found at the end of the path from its string representation. Nil
+// pointers, maps and too-short slices are allocated as they are walked.
+func setPath(v reflect.Value, path []string, value string) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		return setPath(v.Elem(), path, value)
+	}
+
+	if len(path) == 0 {
+		return setValue(v, value)
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if isScalar(v) {
+			break
+		}
+
+		fv, ok := fieldByName(v, path[0])
+		if !ok {
+			return fmt.Errorf("unknown field %q in %s", path[0], v.Type())
+		}
+
+		err := setPath(fv, path[1:], value)
+		if err != nil {
+			return wrapPath(path[0], err)
+		}
+
+		return nil
+
+	case reflect.Slice, reflect.Array:
+		idx, err := strconv.Atoi(path[0])
+		if err != nil || idx < 0 {
+			return fmt.Errorf("invalid index %q for %s", path[0], v.Type())
+		}
+
+		if idx >= v.Len() {
+			if v.Kind() == reflect.Array {
+				return fmt.Errorf("index %d out of range for %s", idx, v.Type())
+			}
+
+			grown := reflect.MakeSlice(v.Type(), idx+1, idx+1)
+			n := reflect.Copy(grown, v)
+
+			// never leave nil pointers in the gaps between existing
+			// elements and the new one.
+			if grown.Type().Elem().Kind() == reflect.Pointer {
+				for i := n; i < grown.Len(); i++ {
+					grown.Index(i).Set(reflect.New(grown.Type().Elem().Elem()))
+				}
+			}
+
+			v.Set(grown)
+		}
+
+		err = setPath(v.Index(idx), path[1:], value)
+		if err != nil {
+			return wrapPath("["+path[0]+"]", err)
+		}
+
+		return nil
+
+	case reflect.Map:
+		key := reflect.New(v.Type().Key()).Elem()
+
+		err := setValue(key, path[0])
+		if err != nil {
+			return fmt.Errorf("invalid key %q for %s: %w", path[0], v.Type(), err)
+		}
+
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+
+		// map elements are not addressable, so modify a copy of the
+		// existing element and store it back.
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if existing := v.MapIndex(key); existing.IsValid() {
+			elem.Set(existing)
+		}
+
+		err = setPath(elem, path[1:], value)
+		if err != nil {
+			return wrapPath(path[0], err)
+		}
+
+		v.SetMapIndex(key, elem)
+		return nil
+	}
+
+	return fmt.Errorf("cannot set %q on %s", strings.Join(path, "."), v.Type())
+}
+
+// setValue sets v from its string representation, using the same conversion
+// rules as YAML scalars, or from a YAML document for slices, maps and structs.
+func setValue(v reflect.Value, value string) error {
+	if v.Kind() == reflect.String && !isUnmarshaler(v) {
+		v.SetString(value)
+		return nil
+	}
+
+	node := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+
+	t := v.Type()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		if isUnmarshaler(reflect.New(t).Elem()) {
+			break
+		}
+
+		doc := &yaml.Node{}
+
+		err := yaml.Unmarshal([]byte(value), doc)
+		if err != nil {
+			return err
+		}
+
+		if len(doc.Content) > 0 {
+			node = doc.Content[0]
+		}
+	}
+
+	dst := reflect.New(v.Type())
+
+	err := node.Decode(dst.Interface())
+	if err != nil {
+		return unwrapYAML(err)
+	}
+
+	v.Set(dst.Elem())
+	return nil
+}
+
+// isScalar returns whether a struct is represented as a scalar value in YAML,
+// rather than a mapping of its fields.
+func isScalar(v reflect.Value) bool {
+	if v.Kind() != reflect.Struct {
+		return true
+	}
+
+	return isUnmarshaler(v)
+}
+
+// isUnmarshaler returns whether v implements its own decoding from YAML or
+// text.
+func isUnmarshaler(v reflect.Value) bool {
+	ptr := reflect.New(v.Type()).Interface()
+
+	switch ptr.(type) {
+	case yaml.Unmarshaler, encoding.TextUnmarshaler:
+		return true
+	}
+
+	return false
+}
+
+// unwrapYAML removes the line information from errors returned when decoding
+// a single, synthetic yaml.Node, which is meaningless to users.
+func unwrapYAML(err error) error {
+	if te, ok := err.(*yaml.TypeError); ok && len(te.Errors) > 0 {
+		msg := te.Errors[0]
+		if _, after, ok := strings.Cut(msg, ": "); ok && strings.HasPrefix(msg, "line ") {
+			msg = after
+		}
+
+		return fmt.Errorf("%s", msg)
+	}
+
+	return err
+}
+
+// pathError describes an error setting the value of a field, including the
+// path to the field.
+type pathError struct {
+	path string
+	err  error
+}
+
+func (pe *pathError) Error() string {
+	return pe.path + ": " + pe.err.Error()
+}
+
+func (pe *pathError) Unwrap() error {
+	return pe.err
+}
+
+// wrapPath prepends the parent element to the path of err.
+func wrapPath(parent string, err error) error {
+	pe, ok := err.(*pathError)
+	if !ok {
+		return &pathError{path: parent, err: err}
+	}
+
+	if strings.HasPrefix(pe.path, "[") {
+		return &pathError{path: parent + pe.path, err: pe.err}
+	}
+
+	return &pathError{path: parent + "." + pe.path, err: pe.err}
+}
+
+// formatPath returns the canonical form of a path walked by setPath from the
+// type t, as used by Origins, such as "streams[0].nats.password".
+func formatPath(t reflect.Type, path []string) string {
+	b := strings.Builder{}
+
+	for _, p := range path {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+
+		switch t.Kind() {
+		case reflect.Struct:
+			name, ft, ok := fieldTypeByName(t, p)
+			if !ok {
+				name, ft = p, reflect.TypeOf("")
+			}
+
+			if b.Len() > 0 {
+				b.WriteString(".")
+			}
+
+			b.WriteString(name)
+			t = ft
+
+		case reflect.Slice, reflect.Array:
+			b.WriteString("[" + p + "]")
+			t = t.Elem()
+
+		case reflect.Map:
+			if b.Len() > 0 {
+				b.WriteString(".")
+			}
+
+			b.WriteString(p)
+			t = t.Elem()
+
+		default:
+			b.WriteString("." + p)
+		}
+	}
+
+	return b.String()
+}
+
+// fieldTypeByName is the equivalent of fieldByName for types, returning the
+// yaml name and type of the field.
+func fieldTypeByName(t reflect.Type, name string) (string, reflect.Type, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		fn, inline, skip := yamlName(t.Field(i))
+		if skip {
+			continue
+		}
+
+		ft := t.Field(i).Type
+
+		if inline {
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				if n, found, ok := fieldTypeByName(ft, name); ok {
+					return n, found, true
+				}
+			}
+
+			continue
+		}
+
+		if strings.EqualFold(fn, name) {
+			return fn, ft, true
+		}
+	}
+
+	return "", nil, false
+}
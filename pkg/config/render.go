@@ -0,0 +1,365 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// commentWidth is the width doc comments are wrapped to when rendered.
+const commentWidth = 78
+
+// Render marshals the configuration v to YAML, commented with the registered
+// doc comments of its types and fields (see RegisterDocs). Secrets are always
+// redacted, see Redact. The current version of the type is included if it has
+// migrations, see Migrator.
+func Render(v any) ([]byte, error) {
+	n, err := encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	t := reflect.TypeOf(v)
+	commentNode(t, n, 0)
+	versionNode(v, n)
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	doc := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{n}}
+	if text := typeDoc(t); text != "" {
+		doc.HeadComment = comment(text, 0)
+	}
+
+	return write(doc)
+}
+
+// Dump marshals the effective configuration v to YAML with secrets redacted,
+// see Redact. If origins are given, each value is commented with where it was
+// loaded from.
+func Dump(v any, origins Origins) ([]byte, error) {
+	n, err := encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if origins != nil {
+		nodeOriginComments(n, "", origins)
+	}
+
+	versionNode(v, n)
+
+	return write(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{n}})
+}
+
+// Redact returns the configuration v as generic maps, slices and scalars with
+// secrets redacted, for example to be logged as a structured attribute. Values
+// of the Secret type and fields tagged `secret:"true"` are secrets.
+func Redact(v any) (map[string]any, error) {
+	n, err := encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	m := map[string]any{}
+
+	err = n.Decode(&m)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// encode marshals v into a yaml.Node with secrets redacted.
+func encode(v any) (*yaml.Node, error) {
+	n := &yaml.Node{}
+
+	err := n.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	redactNode(reflect.TypeOf(v), n, false)
+
+	return n, nil
+}
+
+// write returns the YAML document doc.
+func write(doc *yaml.Node) ([]byte, error) {
+	b := &bytes.Buffer{}
+	b.WriteString("---\n")
+
+	enc := yaml.NewEncoder(b)
+	enc.SetIndent(2)
+
+	err := enc.Encode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = enc.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// RenderDefaults renders the configuration v with defaults applied, see
+// SetDefaults, where empty slices of structs without defaults are given a
+// single example item so every field is documented. Fields left zero are set
+// to the value of their example tag, decoded as YAML like default tags, so
+// required fields without defaults render as a valid configuration, for
+// example `example:"[nats://localhost:4222]"`.
+func RenderDefaults(v any) ([]byte, error) {
+	err := exemplify(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+
+	err = SetDefaults(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return Render(v)
+}
+
+// exemplify allocates nil struct pointers, adds an example item to empty
+// slices of structs and applies the example tags of zero fields in v.
+func exemplify(v reflect.Value) error {
+	errs := []error{}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			if v.Type().Elem().Kind() != reflect.Struct || !v.CanSet() {
+				return nil
+			}
+
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		return exemplify(v.Elem())
+
+	case reflect.Struct:
+		if isScalar(v) {
+			return nil
+		}
+
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+
+			name, inline, skip := yamlName(f)
+			if skip {
+				continue
+			}
+
+			if example, ok := f.Tag.Lookup("example"); ok && !inline && v.Field(i).IsZero() {
+				err := setValue(v.Field(i), example)
+				if err != nil {
+					errs = append(errs, &pathError{path: name, err: fmt.Errorf("invalid example %q: %w", example, err)})
+				}
+
+				continue
+			}
+
+			// slices with defaults are exemplified by them instead.
+			if f.Type.Kind() == reflect.Slice && f.Tag.Get("default") != "" {
+				continue
+			}
+
+			err := exemplify(v.Field(i))
+			if err != nil {
+				if !inline {
+					err = wrapPath(name, err)
+				}
+
+				errs = append(errs, err)
+			}
+		}
+
+	case reflect.Slice:
+		elem := v.Type().Elem()
+		for elem.Kind() == reflect.Pointer {
+			elem = elem.Elem()
+		}
+
+		if v.Len() == 0 && elem.Kind() == reflect.Struct && !isScalar(reflect.New(elem).Elem()) {
+			v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		}
+
+		for i := 0; i < v.Len(); i++ {
+			err := exemplify(v.Index(i))
+			if err != nil {
+				errs = append(errs, wrapPath(fmt.Sprintf("[%d]", i), err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// redactNode replaces the values of fields tagged `secret:"true"` in the node
+// n of type t with Redacted, or every value if secret is set. Values of the
+// Secret type are already redacted when they are marshaled.
+func redactNode(t reflect.Type, n *yaml.Node, secret bool) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case n.Kind == yaml.ScalarNode:
+		if secret && n.Value != "" && n.ShortTag() != "!!null" {
+			n.Value, n.Tag, n.Style = Redacted, "!!str", 0
+		}
+
+	case secret || t == nil:
+		for _, c := range n.Content {
+			redactNode(nil, c, secret)
+		}
+
+	case t.Kind() == reflect.Struct && n.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			_, f, ok := structField(t, n.Content[i].Value)
+			if !ok {
+				continue
+			}
+
+			redactNode(f.Type, n.Content[i+1], f.Tag.Get("secret") == "true")
+		}
+
+	case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && n.Kind == yaml.SequenceNode:
+		for _, item := range n.Content {
+			redactNode(t.Elem(), item, false)
+		}
+
+	case t.Kind() == reflect.Map && n.Kind == yaml.MappingNode:
+		for i := 1; i < len(n.Content); i += 2 {
+			redactNode(t.Elem(), n.Content[i], false)
+		}
+	}
+}
+
+// nodeOriginComments adds the origin of each value in n at path as a line
+// comment.
+func nodeOriginComments(n *yaml.Node, path string, origins Origins) {
+	if origin, ok := origins[path]; ok && path != "" {
+		n.LineComment = "# " + origin
+	}
+
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			nodeOriginComments(n.Content[i+1], joinPath(path, n.Content[i].Value), origins)
+		}
+
+	case yaml.SequenceNode:
+		for i, item := range n.Content {
+			nodeOriginComments(item, path+"["+strconv.Itoa(i)+"]", origins)
+		}
+	}
+}
+
+// commentNode adds the doc comments of the fields of t to the keys of the
+// mapping n, recursively, where depth is the indentation of n.
+func commentNode(t reflect.Type, n *yaml.Node, depth int) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t.Kind() == reflect.Struct && n.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			parent, f, ok := structField(t, n.Content[i].Value)
+			if !ok {
+				continue
+			}
+
+			if doc := fieldDoc(parent, f); doc != "" {
+				n.Content[i].HeadComment = comment(doc, depth)
+			}
+
+			commentNode(f.Type, n.Content[i+1], depth+2)
+		}
+
+	case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && n.Kind == yaml.SequenceNode:
+		for _, item := range n.Content {
+			commentNode(t.Elem(), item, depth+2)
+		}
+
+	case t.Kind() == reflect.Map && n.Kind == yaml.MappingNode:
+		for i := 1; i < len(n.Content); i += 2 {
+			commentNode(t.Elem(), n.Content[i], depth+2)
+		}
+	}
+}
+
+// structField returns the field of struct t with the given yaml name and the
+// struct it was declared in, searching inlined structs.
+func structField(t reflect.Type, name string) (reflect.Type, reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		fn, inline, skip := yamlName(f)
+		if skip {
+			continue
+		}
+
+		if inline {
+			ft := f.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				if parent, found, ok := structField(ft, name); ok {
+					return parent, found, true
+				}
+			}
+
+			continue
+		}
+
+		if fn == name {
+			return t, f, true
+		}
+	}
+
+	return nil, reflect.StructField{}, false
+}
+
+// comment formats text as a YAML comment wrapped to commentWidth, less the
+// indentation the comment is written at.
+func comment(text string, indent int) string {
+	width := max(commentWidth-indent, commentWidth/2)
+
+	lines := []string{}
+
+	for i, p := range strings.Split(text, "\n\n") {
+		if i > 0 {
+			lines = append(lines, "#")
+		}
+
+		line := "#"
+		for _, word := range strings.Fields(p) {
+			if len(line)+1+len(word) > width && line != "#" {
+				lines = append(lines, line)
+				line = "#"
+			}
+
+			line += " " + word
+		}
+
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type renderServer struct {
+	Addr    string        `yaml:"addr" example:"localhost:8080" validate:"required,hostport"`
+	Timeout time.Duration `yaml:"timeout" default:"5s"`
+}
+
+type renderConfig struct {
+	Name    string          `yaml:"name" example:"example" validate:"required"`
+	Servers []*renderServer `yaml:"servers" validate:"required"`
+	Hosts   []string        `yaml:"hosts" example:"[a, b]" validate:"required"`
+	Token   Secret          `yaml:"token"`
+}
+
+func TestRenderDefaultsLoads(t *testing.T) {
+	b, err := RenderDefaults(&renderConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yml")
+
+	err = os.WriteFile(path, b, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &renderConfig{}
+
+	err = FromFile(path, cfg)
+	if err != nil {
+		t.Fatalf("rendered defaults don't load: %v\n%s", err, b)
+	}
+
+	if cfg.Name != "example" {
+		t.Errorf("name = %q, want example", cfg.Name)
+	}
+
+	if len(cfg.Servers) != 1 || cfg.Servers[0].Addr != "localhost:8080" || cfg.Servers[0].Timeout != 5*time.Second {
+		t.Errorf("servers = %+v, want one example server", cfg.Servers)
+	}
+
+	if strings.Join(cfg.Hosts, ",") != "a,b" {
+		t.Errorf("hosts = %v, want [a b]", cfg.Hosts)
+	}
+}
+
+func TestRenderDefaultsKeepsValues(t *testing.T) {
+	cfg := &renderConfig{Name: "set"}
+
+	_, err := RenderDefaults(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Name != "set" {
+		t.Errorf("name = %q, want the value already set", cfg.Name)
+	}
+}
+
+func TestRenderDefaultsInvalidExample(t *testing.T) {
+	type invalid struct {
+		Port int `yaml:"port" example:"http"`
+	}
+
+	_, err := RenderDefaults(&invalid{})
+	if err == nil || !strings.Contains(err.Error(), "port") {
+		t.Fatalf("err = %v, want an invalid example of port", err)
+	}
+}
+
+type dumpNATS struct {
+	Servers  []string `yaml:"servers"`
+	User     string   `yaml:"user"`
+	Password Secret   `yaml:"password"`
+}
+
+type dumpConfig struct {
+	NATS   dumpNATS            `yaml:"nats"`
+	Key    string              `yaml:"key" secret:"true"`
+	Tokens map[string]Secret   `yaml:"tokens"`
+	Keys   []string            `yaml:"keys" secret:"true"`
+	Named  map[string]dumpNATS `yaml:"named"`
+	Empty  Secret              `yaml:"empty"`
+}
+
+func TestDump(t *testing.T) {
+	cfg := &dumpConfig{
+		NATS:   dumpNATS{Servers: []string{"nats://localhost:4222"}, User: "user", Password: "hunter2"},
+		Key:    "abc",
+		Tokens: map[string]Secret{"a": "abc"},
+		Keys:   []string{"abc", "def"},
+		Named:  map[string]dumpNATS{"x": {Password: "hunter2"}},
+	}
+
+	origins := Origins{
+		"nats.servers[0]": "config.yml:3",
+		"nats.password":   "env:SVC__NATS__PASSWORD",
+		"key":             "flag:-key",
+	}
+
+	b, err := Dump(cfg, origins)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dump := string(b)
+
+	for _, secret := range []string{"hunter2", "abc", "def"} {
+		if strings.Contains(dump, secret) {
+			t.Errorf("dump reveals %s:\n%s", secret, dump)
+		}
+	}
+
+	for _, want := range []string{
+		"- nats://localhost:4222 # config.yml:3",
+		"user: user\n",
+		"password: '" + Redacted + "' # env:SVC__NATS__PASSWORD",
+		"key: '" + Redacted + "' # flag:-key",
+		"a: '" + Redacted + "'",
+		"empty: \"\"",
+	} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("dump doesn't contain %q:\n%s", want, dump)
+		}
+	}
+
+	// the configuration itself is unchanged.
+	if cfg.NATS.Password.Value() != "hunter2" || cfg.Key != "abc" {
+		t.Errorf("config = %+v, want unchanged", cfg)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	m, err := Redact(&dumpConfig{
+		NATS: dumpNATS{User: "user", Password: "hunter2"},
+		Keys: []string{"abc"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nats := m["nats"].(map[string]any)
+	if nats["user"] != "user" || nats["password"] != Redacted {
+		t.Errorf("nats = %v, want the password redacted", nats)
+	}
+
+	if keys := m["keys"].([]any); len(keys) != 1 || keys[0] != Redacted {
+		t.Errorf("keys = %v, want each redacted", keys)
+	}
+}
@@ -0,0 +1,292 @@
+package config
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaDialect is the JSON Schema dialect generated by JSONSchema.
+const SchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a subset of JSON Schema sufficient to describe configuration
+// types, as understood by editors with YAML language servers.
+type Schema struct {
+	Schema      string `json:"$schema,omitempty"`
+	ID          string `json:"$id,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	Type       string             `json:"type,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+	OneOf      []*Schema          `json:"oneOf,omitempty"`
+
+	// AdditionalProperties is either a *Schema or false.
+	AdditionalProperties any `json:"additionalProperties,omitempty"`
+
+	Enum      []any    `json:"enum,omitempty"`
+	Default   any      `json:"default,omitempty"`
+	Format    string   `json:"format,omitempty"`
+	Pattern   string   `json:"pattern,omitempty"`
+	WriteOnly bool     `json:"writeOnly,omitempty"`
+	Minimum   *float64 `json:"minimum,omitempty"`
+	Maximum   *float64 `json:"maximum,omitempty"`
+	MinLength *int     `json:"minLength,omitempty"`
+	MaxLength *int     `json:"maxLength,omitempty"`
+	MinItems  *int     `json:"minItems,omitempty"`
+	MaxItems  *int     `json:"maxItems,omitempty"`
+}
+
+// Schemer is optionally implemented by configuration types that decode
+// themselves, to describe what they decode from.
+type Schemer interface {
+	JSONSchema() *Schema
+}
+
+// Patterns used to describe values in schemas.
+const (
+	durationPattern = `^-?([0-9]+(\.[0-9]*)?(ns|us|µs|ms|s|m|h))+$|^0$`
+	hostportPattern = `^(\[[^\]]*\]|[^:\[\]]*):[0-9]{1,5}$`
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	secretType   = reflect.TypeOf(Secret(""))
+	schemerType  = reflect.TypeOf((*Schemer)(nil)).Elem()
+)
+
+// JSONSchema generates a JSON Schema of the configuration type of v, using
+// yaml tags for property names, registered doc comments (see RegisterDocs) for
+// descriptions, validate tags (see Validate) for constraints and default tags
+// (see SetDefaults) for defaults. The reserved version key is described at the
+// top level, see Migrator.
+func JSONSchema(v any) *Schema {
+	s := typeSchema(reflect.TypeOf(v), map[reflect.Type]bool{})
+	s.Schema = SchemaDialect
+
+	if s.Properties != nil {
+		s.Properties[VersionKey] = &Schema{
+			Type:        "integer",
+			Description: "Version of the format of the configuration file, where older versions are migrated when loaded.",
+			Default:     1,
+			Minimum:     ptr(1.0),
+			Maximum:     ptr(float64(Version(v))),
+		}
+	}
+
+	return s
+}
+
+// typeSchema returns the schema of t, where seen contains the struct types
+// being described to prevent infinite recursion.
+func typeSchema(t reflect.Type, seen map[reflect.Type]bool) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if reflect.PointerTo(t).Implements(schemerType) {
+		return reflect.New(t).Interface().(Schemer).JSONSchema()
+	}
+
+	switch {
+	case t == durationType:
+		return &Schema{Type: "string", Pattern: durationPattern}
+
+	case t == secretType:
+		return &Schema{Type: "string", WriteOnly: true}
+
+	case isUnmarshaler(reflect.New(t).Elem()) && t.Kind() != reflect.String:
+		// the type decodes itself from something that can't be
+		// described.
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer"}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: ptr(0.0)}
+
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+
+	case reflect.String:
+		return &Schema{Type: "string"}
+
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: typeSchema(t.Elem(), seen)}
+
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: typeSchema(t.Elem(), seen)}
+
+	case reflect.Struct:
+		if seen[t] {
+			return &Schema{Type: "object"}
+		}
+
+		seen[t] = true
+		defer delete(seen, t)
+
+		s := &Schema{
+			Type:                 "object",
+			Description:          typeDoc(t),
+			Properties:           map[string]*Schema{},
+			AdditionalProperties: false,
+		}
+
+		structSchema(t, s, seen)
+
+		return s
+	}
+
+	return &Schema{}
+}
+
+// structSchema adds the properties of the fields of struct t to s.
+func structSchema(t reflect.Type, s *Schema, seen map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		name, inline, skip := yamlName(f)
+		if skip {
+			continue
+		}
+
+		if inline {
+			ft := f.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				structSchema(ft, s, seen)
+			}
+
+			continue
+		}
+
+		fs := typeSchema(f.Type, seen)
+		if doc := fieldDoc(t, f); doc != "" {
+			fs.Description = doc
+		}
+
+		if def, ok := f.Tag.Lookup("default"); ok {
+			var v any
+			if yaml.Unmarshal([]byte(def), &v) == nil && v != nil {
+				fs.Default = v
+			}
+		}
+
+		// a field with a default is never missing.
+		if ruleSchema(f, fs) && fs.Default == nil {
+			s.Required = append(s.Required, name)
+		}
+
+		s.Properties[name] = fs
+	}
+}
+
+// ruleSchema adds the constraints of the validate tag of f to s, returning
+// whether the field is required.
+func ruleSchema(f reflect.StructField, s *Schema) bool {
+	required := false
+
+	rules := f.Tag.Get("validate")
+	if rules == "" || rules == "-" {
+		return false
+	}
+
+	// rules other than length and bounds apply to the items of slices.
+	items := s
+	if s.Type == "array" && s.Items != nil {
+		items = s.Items
+	}
+
+	for _, rule := range strings.Split(rules, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+
+		switch name {
+		case "required":
+			required = true
+
+			switch s.Type {
+			case "string":
+				s.MinLength = ptr(max(1, deref(s.MinLength)))
+			case "array":
+				s.MinItems = ptr(max(1, deref(s.MinItems)))
+			}
+
+		case "min", "max":
+			boundSchema(s, name, param)
+
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				items.Enum = append(items.Enum, v)
+			}
+
+		case "hostport":
+			items.Pattern = hostportPattern
+
+		case "regexp":
+			items.Format = "regex"
+
+		case "url":
+			items.Format = "uri"
+		}
+	}
+
+	return required
+}
+
+// boundSchema adds a min or max rule to s.
+func boundSchema(s *Schema, rule, param string) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		// bounds such as durations can't be described.
+		return
+	}
+
+	switch s.Type {
+	case "string":
+		if rule == "min" {
+			s.MinLength = ptr(int(n))
+		} else {
+			s.MaxLength = ptr(int(n))
+		}
+
+	case "array":
+		if rule == "min" {
+			s.MinItems = ptr(int(n))
+		} else {
+			s.MaxItems = ptr(int(n))
+		}
+
+	case "integer", "number":
+		if rule == "min" {
+			s.Minimum = ptr(n)
+		} else {
+			s.Maximum = ptr(n)
+		}
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func deref(v *int) int {
+	if v == nil {
+		return 0
+	}
+
+	return *v
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+type schemaServer struct {
+	Addr string `yaml:"addr" validate:"required,hostport"`
+}
+
+type schemaConfig struct {
+	EnvLogs `yaml:",inline"`
+
+	Name     string                   `yaml:"name" validate:"required,max=10"`
+	Password Secret                   `yaml:"password"`
+	Timeout  time.Duration            `yaml:"timeout" default:"5s" validate:"min=1ms"`
+	Retries  uint                     `yaml:"retries" validate:"max=5"`
+	Actions  []string                 `yaml:"actions" default:"[start]" validate:"oneof=start stop"`
+	Servers  []*schemaServer          `yaml:"servers" validate:"required,max=3"`
+	Named    map[string]*schemaServer `yaml:"named"`
+	Next     *schemaConfig            `yaml:"next"`
+	Ignored  string                   `yaml:"-"`
+}
+
+func TestJSONSchema(t *testing.T) {
+	RegisterDocs(reflect.TypeOf(schemaConfig{}).PkgPath(), map[string]string{
+		"schemaConfig":      "schemaConfig configures a test.",
+		"schemaConfig.Name": "Name names the test.",
+		"schemaServer":      "schemaServer configures a server.",
+	})
+
+	s := JSONSchema(&schemaConfig{})
+
+	if s.Schema != SchemaDialect || s.Type != "object" || s.Description != "schemaConfig configures a test." {
+		t.Errorf("schema = %+v, want the documented object", s)
+	}
+
+	if s.AdditionalProperties != false {
+		t.Error("want additional properties disallowed")
+	}
+
+	// required fields with defaults are never missing.
+	if !slices.Equal(s.Required, []string{"name", "servers"}) {
+		t.Errorf("required = %v, want [name servers]", s.Required)
+	}
+
+	names := []string{}
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	want := []string{"actions", "level", "name", "named", "next", "password", "retries", "servers", "timeout", "version"}
+	if !slices.Equal(names, want) {
+		t.Errorf("properties = %v, want %v", names, want)
+	}
+
+	p := s.Properties
+
+	if p["name"].Description != "Name names the test." || deref(p["name"].MinLength) != 1 || deref(p["name"].MaxLength) != 10 {
+		t.Errorf("name = %+v, want documented with lengths", p["name"])
+	}
+
+	if !p["password"].WriteOnly {
+		t.Error("want the password write only")
+	}
+
+	if p["timeout"].Pattern != durationPattern || p["timeout"].Default != "5s" {
+		t.Errorf("timeout = %+v, want a duration defaulting to 5s", p["timeout"])
+	}
+
+	if p["retries"].Type != "integer" || *p["retries"].Minimum != 0 || *p["retries"].Maximum != 5 {
+		t.Errorf("retries = %+v, want an integer from 0 to 5", p["retries"])
+	}
+
+	if items := p["actions"].Items; items == nil || !slices.Equal(items.Enum, []any{"start", "stop"}) {
+		t.Errorf("actions = %+v, want items of start or stop", p["actions"])
+	}
+
+	servers := p["servers"]
+	if deref(servers.MinItems) != 1 || deref(servers.MaxItems) != 3 {
+		t.Errorf("servers = %+v, want 1 to 3 items", servers)
+	}
+
+	if item := servers.Items; item.Description != "schemaServer configures a server." || item.Properties["addr"].Pattern != hostportPattern {
+		t.Errorf("servers items = %+v, want the documented server", item)
+	}
+
+	if named := p["named"].AdditionalProperties.(*Schema); named.Properties["addr"] == nil {
+		t.Errorf("named = %+v, want servers by name", p["named"])
+	}
+
+	// recursive types aren't described again.
+	if next := p["next"]; next.Type != "object" || next.Properties != nil {
+		t.Errorf("next = %+v, want an object", next)
+	}
+
+	if v := p[VersionKey]; v.Default != 1 || *v.Maximum != float64(Version(&schemaConfig{})) {
+		t.Errorf("version = %+v, want 1 up to the current version", v)
+	}
+
+	_, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"log/slog"
+)
+
+// Redacted is the placeholder written in place of the value of a Secret.
+const Redacted = "[REDACTED]"
+
+// Secret is a string holding sensitive configuration, such as a password,
+// that is never revealed when formatted, logged or marshaled. The plaintext is
+// only available from Value.
+type Secret string
+
+// Value returns the plaintext of the Secret.
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// String implements fmt.Stringer, returning Redacted unless the Secret is
+// empty.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+
+	return Redacted
+}
+
+// GoString implements fmt.GoStringer for the %#v verb.
+func (s Secret) GoString() string {
+	return `config.Secret("` + s.String() + `")`
+}
+
+// LogValue implements slog.LogValuer so a Secret is redacted in logs.
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
+
+// MarshalText implements encoding.TextMarshaler so a Secret is redacted when
+// marshaled to JSON or YAML.
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSecret(t *testing.T) {
+	s := Secret("hunter2")
+
+	if s.Value() != "hunter2" {
+		t.Errorf("value = %s, want the plaintext", s.Value())
+	}
+
+	formatted := []string{
+		fmt.Sprint(s),
+		fmt.Sprintf("%v %s %#v", s, s, s),
+		fmt.Sprintf("%+v", struct{ S Secret }{s}),
+	}
+
+	b, err := json.Marshal(map[string]Secret{"s": s})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	formatted = append(formatted, string(b))
+
+	b, err = yaml.Marshal(map[string]Secret{"s": s})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	formatted = append(formatted, string(b))
+
+	buf := &bytes.Buffer{}
+	slog.New(slog.NewTextHandler(buf, nil)).Info("secret", "s", s)
+
+	formatted = append(formatted, buf.String())
+
+	for _, f := range formatted {
+		if strings.Contains(f, "hunter2") || !strings.Contains(f, Redacted) {
+			t.Errorf("%s reveals the secret", f)
+		}
+	}
+
+	if Secret("").String() != "" {
+		t.Error("want an empty Secret formatted as empty")
+	}
+}
+
+func TestSecretDecodes(t *testing.T) {
+	var v struct {
+		S Secret `yaml:"s"`
+	}
+
+	err := yaml.Unmarshal([]byte("s: hunter2\n"), &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.S.Value() != "hunter2" {
+		t.Errorf("value = %s, want the plaintext decoded", v.S.Value())
+	}
+}
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DecodeError describes a problem decoding a value from a configuration file,
+// such as an unknown or duplicate key, or a value of the wrong type.
+type DecodeError struct {
+	// File, Line and Column are the position of the problem.
+	File   string
+	Line   int
+	Column int
+
+	// Field is the path to the field in the configuration object.
+	Field string
+
+	// Message describes the problem.
+	Message string
+}
+
+func (de *DecodeError) Error() string {
+	pos := de.File + ":" + strconv.Itoa(de.Line) + ":" + strconv.Itoa(de.Column)
+	if de.Field == "" {
+		return pos + ": " + de.Message
+	}
+
+	return pos + ": " + de.Field + ": " + de.Message
+}
+
+// DecodeErrors aggregates every DecodeError found in the configuration files,
+// so all problems can be reported at once.
+type DecodeErrors []*DecodeError
+
+func (de DecodeErrors) Error() string {
+	msgs := make([]string, len(de))
+	for i, err := range de {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns each DecodeError for use with errors.Is and errors.As.
+func (de DecodeErrors) Unwrap() []error {
+	errs := make([]error, len(de))
+	for i, err := range de {
+		errs[i] = err
+	}
+
+	return errs
+}
+
+// checker checks YAML documents against the configuration type they are to be
+// decoded into, collecting every problem found.
+type checker struct {
+	files        map[*yaml.Node]string
+	allowUnknown bool
+	errs         DecodeErrors
+}
+
+// errorf records a problem with the node n.
+func (c *checker) errorf(n *yaml.Node, path, format string, args ...any) {
+	c.errs = append(c.errs, &DecodeError{
+		File:    c.files[n],
+		Line:    n.Line,
+		Column:  n.Column,
+		Field:   path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// duplicates records every mapping key in n that is defined more than once
+// within the same mapping.
+func (c *checker) duplicates(n *yaml.Node, path string) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, child := range n.Content {
+			c.duplicates(child, path)
+		}
+
+	case yaml.MappingNode:
+		seen := map[string]*yaml.Node{}
+
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i]
+
+			if first, ok := seen[key.Value]; ok && key.Kind == yaml.ScalarNode {
+				c.errorf(key, joinPath(path, key.Value), "duplicate key, first defined at line %d", first.Line)
+			} else {
+				seen[key.Value] = key
+			}
+
+			c.duplicates(n.Content[i+1], joinPath(path, key.Value))
+		}
+
+	case yaml.SequenceNode:
+		for i, child := range n.Content {
+			c.duplicates(child, path+"["+strconv.Itoa(i)+"]")
+		}
+	}
+}
+
+// check records every unknown key and value that can't be decoded into the
+// type t in the node n.
+func (c *checker) check(t reflect.Type, n *yaml.Node, path string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, child := range n.Content {
+			c.check(t, child, path)
+		}
+		return
+
+	case yaml.AliasNode:
+		if n.Alias != nil {
+			c.check(t, n.Alias, path)
+		}
+		return
+	}
+
+	if n.Kind == yaml.ScalarNode && n.ShortTag() == "!!null" {
+		return
+	}
+
+	// types that decode themselves, or can hold anything, are checked by
+	// decoding them.
+	if t.Kind() == reflect.Interface || isUnmarshaler(reflect.New(t).Elem()) {
+		c.decode(t, n, path)
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			c.errorf(n, path, "expected a mapping, got %s", kindName(n))
+			return
+		}
+
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+
+			// merge keys are resolved by the decoder.
+			if key.Value == "<<" {
+				continue
+			}
+
+			_, f, ok := structField(t, key.Value)
+			if !ok {
+				if !c.allowUnknown {
+					c.errorf(key, joinPath(path, key.Value), "unknown key in %s", t)
+				}
+				continue
+			}
+
+			c.check(f.Type, value, joinPath(path, key.Value))
+		}
+
+	case reflect.Slice, reflect.Array:
+		if n.Kind != yaml.SequenceNode {
+			c.errorf(n, path, "expected a sequence, got %s", kindName(n))
+			return
+		}
+
+		for i, item := range n.Content {
+			c.check(t.Elem(), item, path+"["+strconv.Itoa(i)+"]")
+		}
+
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			c.errorf(n, path, "expected a mapping, got %s", kindName(n))
+			return
+		}
+
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+
+			c.decode(t.Key(), key, joinPath(path, key.Value))
+			c.check(t.Elem(), value, joinPath(path, key.Value))
+		}
+
+	default:
+		if n.Kind != yaml.ScalarNode {
+			c.errorf(n, path, "expected a %s value, got %s", t, kindName(n))
+			return
+		}
+
+		c.decode(t, n, path)
+	}
+}
+
+// decode records an error if the node n can't be decoded into the type t.
+func (c *checker) decode(t reflect.Type, n *yaml.Node, path string) {
+	err := n.Decode(reflect.New(t).Interface())
+	if err != nil {
+		c.errorf(n, path, "%s", unwrapYAML(err))
+	}
+}
+
+// joinPath returns the path to the key of a mapping at path.
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+// kindName describes the kind of a node for users.
+func kindName(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a sequence"
+	}
+
+	return "scalar " + strconv.Quote(n.Value)
+}
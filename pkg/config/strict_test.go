@@ -0,0 +1,139 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type strictServer struct {
+	Addr    string        `yaml:"addr"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type strictConfig struct {
+	EnvLogs `yaml:",inline"`
+
+	Server  strictServer          `yaml:"server"`
+	Servers []strictServer        `yaml:"servers"`
+	Limits  map[string]int        `yaml:"limits"`
+	Named   map[int]*strictServer `yaml:"named"`
+	Hosts   []string              `yaml:"hosts"`
+}
+
+func TestLoaderStrict(t *testing.T) {
+	dir := t.TempDir()
+
+	base := writeTestFile(t, dir, "config.yml", `
+level: debug
+server:
+  adr: localhost:8080
+  timeout: soon
+servers:
+- addr: a
+  addr: b
+limits:
+  a: many
+named:
+  x: {addr: a}
+hosts: a
+`)
+
+	local := writeTestFile(t, dir, "local.yml", `
+unknown: 1
+server: localhost
+`)
+
+	_, err := (&Loader{}).FromFiles([]string{base, local}, &strictConfig{})
+
+	var errs DecodeErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("err = %v, want DecodeErrors", err)
+	}
+
+	// every problem is reported with the file it was found in.
+	want := []string{
+		base + ":8:3: servers[0].addr: duplicate key, first defined at line 7",
+		local + ":2:1: unknown: unknown key in config.strictConfig",
+		local + ":3:9: server: expected a mapping, got scalar \"localhost\"",
+		base + ":10:6: limits.a: cannot unmarshal !!str `many` into int",
+		base + ":12:3: named.x: cannot unmarshal !!str `x` into int",
+		base + ":13:8: hosts: expected a sequence, got scalar \"a\"",
+	}
+
+	got := map[string]bool{}
+	for _, de := range errs {
+		got[de.Error()] = true
+	}
+
+	for _, w := range want {
+		if !got[w] {
+			t.Errorf("errors = %v, want %s", err, w)
+		}
+	}
+
+	if len(errs) != len(want) {
+		t.Errorf("errors = %v, want %d", err, len(want))
+	}
+}
+
+func TestLoaderStrictValues(t *testing.T) {
+	dir := t.TempDir()
+
+	path := writeTestFile(t, dir, "config.yml", `
+server:
+  adr: localhost:8080
+  timeout: soon
+`)
+
+	_, err := (&Loader{}).FromFiles([]string{path}, &strictConfig{})
+
+	want := []string{
+		path + ":3:3: server.adr: unknown key in config.strictServer",
+		path + ":4:12: server.timeout: cannot unmarshal !!str `soon` into time.Duration",
+	}
+
+	var errs DecodeErrors
+	if !errors.As(err, &errs) || len(errs) != len(want) {
+		t.Fatalf("err = %v, want %d DecodeErrors", err, len(want))
+	}
+
+	for i, de := range errs {
+		if de.Error() != want[i] {
+			t.Errorf("errors[%d] = %s, want %s", i, de, want[i])
+		}
+	}
+}
+
+func TestLoaderAllowUnknownFields(t *testing.T) {
+	dir := t.TempDir()
+
+	path := writeTestFile(t, dir, "config.yml", `
+unknown: 1
+server:
+  addr: localhost:8080
+  adr: localhost:8081
+defaults: &defaults
+  timeout: 1s
+servers:
+- <<: *defaults
+  addr: a
+`)
+
+	cfg := &strictConfig{}
+
+	_, err := (&Loader{AllowUnknownFields: true}).FromFiles([]string{path}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Server.Addr != "localhost:8080" || len(cfg.Servers) != 1 || cfg.Servers[0].Timeout != time.Second {
+		t.Errorf("config = %+v, want the known keys decoded", cfg)
+	}
+
+	// unknown keys are still reported by default.
+	_, err = (&Loader{}).FromFiles([]string{path}, &strictConfig{})
+	if err == nil {
+		t.Error("want an error for unknown keys")
+	}
+}
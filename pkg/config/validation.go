@@ -1,7 +1,16 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Validator is optionally implemented by configuration types that allow this
@@ -27,8 +36,13 @@ func (ve *ValidationError) Error() string {
 
 // Wrap returns the ValidationError but prepended with a parent path.
 func (ve *ValidationError) Wrap(parent string) *ValidationError {
+	field := parent
+	if ve.Field != "" {
+		field += "." + ve.Field
+	}
+
 	return &ValidationError{
-		Field:   parent + "." + ve.Field,
+		Field:   field,
 		Message: ve.Message,
 	}
 }
@@ -36,8 +50,356 @@ func (ve *ValidationError) Wrap(parent string) *ValidationError {
 // WrapIdx returns the ValidationError but prepended by the parent path and
 // array index of the item, where there are multiple.
 func (ve *ValidationError) WrapIdx(parent string, idx int) *ValidationError {
-	return &ValidationError{
-		Field:   parent + "[" + strconv.Itoa(idx) + "]." + ve.Field,
+	return (&ValidationError{
+		Field:   ve.Field,
 		Message: ve.Message,
+	}).Wrap(parent + "[" + strconv.Itoa(idx) + "]")
+}
+
+// ValidationErrors aggregates every ValidationError found in a configuration,
+// so all problems can be reported at once.
+type ValidationErrors []*ValidationError
+
+func (ve ValidationErrors) Error() string {
+	msgs := make([]string, len(ve))
+	for i, err := range ve {
+		msgs[i] = err.Error()
 	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns each ValidationError for use with errors.Is and errors.As.
+func (ve ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(ve))
+	for i, err := range ve {
+		errs[i] = err
+	}
+
+	return errs
+}
+
+// Wrap returns the ValidationErrors each prepended with a parent path.
+func (ve ValidationErrors) Wrap(parent string) ValidationErrors {
+	wrapped := make(ValidationErrors, len(ve))
+	for i, err := range ve {
+		wrapped[i] = err.Wrap(parent)
+	}
+
+	return wrapped
+}
+
+// WrapIdx returns the ValidationErrors each prepended by the parent path and
+// array index of the item.
+func (ve ValidationErrors) WrapIdx(parent string, idx int) ValidationErrors {
+	wrapped := make(ValidationErrors, len(ve))
+	for i, err := range ve {
+		wrapped[i] = err.WrapIdx(parent, idx)
+	}
+
+	return wrapped
+}
+
+// Validate walks the configuration v, including nested structs, pointers,
+// slices and maps, checking the rules given by the validate tag of each field
+// and calling the Validate method of every type implementing Validator. All
+// failures are returned together as ValidationErrors, or nil if v is valid.
+//
+// Rules are separated by commas, for example `validate:"required,hostport"`:
+//
+//	required   must not be empty, zero or nil.
+//	min=N      minimum length of strings, slices and maps, or minimum value of
+//	           numbers and durations.
+//	max=N      maximum length or value, like min.
+//	oneof=A B  must be one of the space separated values.
+//	hostport   must be a host:port address, where the host may be empty.
+//	regexp     must be a valid regular expression.
+//	url        must be an absolute URL.
+//
+// Rules other than required, min and max are applied to each item of slices.
+func Validate(v any) error {
+	errs := validateValue(reflect.ValueOf(v))
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+// validateValue validates v and everything it contains, returning errors with
+// paths relative to v.
+func validateValue(v reflect.Value) ValidationErrors {
+	errs := ValidationErrors{}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+
+		return validateValue(v.Elem())
+
+	case reflect.Struct:
+		errs = append(errs, validateStruct(v)...)
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			errs = append(errs, validateValue(v.Index(i)).WrapIdx("", i)...)
+		}
+
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			errs = append(errs, validateValue(iter.Value()).Wrap(fmt.Sprint(iter.Key().Interface()))...)
+		}
+	}
+
+	errs = append(errs, callValidator(v)...)
+
+	return errs
+}
+
+// validateStruct checks the rules of each field of the struct v, then
+// validates the field values themselves.
+func validateStruct(v reflect.Value) ValidationErrors {
+	errs := ValidationErrors{}
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		name, inline, skip := yamlName(t.Field(i))
+		if skip {
+			continue
+		}
+
+		fv := v.Field(i)
+
+		if inline {
+			errs = append(errs, validateValue(fv)...)
+			continue
+		}
+
+		rules := t.Field(i).Tag.Get("validate")
+		if rules != "" && rules != "-" {
+			errs = append(errs, wrapField(name, checkRules(fv, rules))...)
+		}
+
+		errs = append(errs, wrapField(name, validateValue(fv))...)
+	}
+
+	return errs
+}
+
+// wrapField prepends the field name to the path of each error, where errors
+// of slice items already begin with their index.
+func wrapField(name string, errs ValidationErrors) ValidationErrors {
+	wrapped := make(ValidationErrors, len(errs))
+
+	for i, err := range errs {
+		if strings.HasPrefix(err.Field, "[") {
+			wrapped[i] = &ValidationError{Field: name + err.Field, Message: err.Message}
+		} else {
+			wrapped[i] = err.Wrap(name)
+		}
+	}
+
+	return wrapped
+}
+
+// callValidator calls the Validate method of v if it implements Validator,
+// converting the error into ValidationErrors.
+func callValidator(v reflect.Value) ValidationErrors {
+	var val Validator
+
+	if v.CanAddr() {
+		val, _ = v.Addr().Interface().(Validator)
+	} else if v.CanInterface() {
+		val, _ = v.Interface().(Validator)
+	}
+
+	if val == nil {
+		return nil
+	}
+
+	err := val.Validate()
+	if err == nil {
+		return nil
+	}
+
+	var ve *ValidationError
+	var ves ValidationErrors
+
+	switch {
+	case errors.As(err, &ves):
+		return ves
+
+	case errors.As(err, &ve):
+		return ValidationErrors{ve}
+	}
+
+	return ValidationErrors{{Message: err.Error()}}
+}
+
+// checkRules checks the comma separated rules against the field value v,
+// returning errors relative to the field.
+func checkRules(v reflect.Value, rules string) ValidationErrors {
+	errs := ValidationErrors{}
+
+	for _, rule := range strings.Split(rules, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+
+		switch name {
+		case "required":
+			if v.IsZero() || (isCollection(v) && v.Len() == 0) {
+				// the remaining rules are meaningless for a missing value.
+				return append(errs, &ValidationError{Message: "is required"})
+			}
+
+		case "min", "max":
+			msg := checkBound(v, name, param)
+			if msg != "" {
+				errs = append(errs, &ValidationError{Message: msg})
+			}
+
+		case "oneof", "hostport", "regexp", "url":
+			errs = append(errs, checkItems(v, name, param)...)
+
+		default:
+			errs = append(errs, &ValidationError{Message: fmt.Sprintf("unknown validation rule %q", name)})
+		}
+	}
+
+	return errs
+}
+
+// checkItems applies a rule to v, or each item of v if it is a slice.
+func checkItems(v reflect.Value, rule, param string) ValidationErrors {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+
+		v = v.Elem()
+	}
+
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		errs := ValidationErrors{}
+
+		for i := 0; i < v.Len(); i++ {
+			errs = append(errs, checkItems(v.Index(i), rule, param).WrapIdx("", i)...)
+		}
+
+		return errs
+	}
+
+	if v.Kind() != reflect.String {
+		return ValidationErrors{{Message: fmt.Sprintf("rule %q cannot be applied to %s", rule, v.Type())}}
+	}
+
+	s := v.String()
+	if s == "" {
+		// empty values are only invalid if required.
+		return nil
+	}
+
+	msg := ""
+
+	switch rule {
+	case "oneof":
+		if !slices.Contains(strings.Fields(param), s) {
+			msg = fmt.Sprintf("must be one of %s, got %q", strings.Join(strings.Fields(param), ", "), s)
+		}
+
+	case "hostport":
+		_, port, err := net.SplitHostPort(s)
+		if err != nil {
+			msg = fmt.Sprintf("must be a host:port address, got %q", s)
+		} else if n, err := strconv.ParseUint(port, 10, 16); err != nil || (n == 0 && port != "0") {
+			msg = fmt.Sprintf("must have a valid port, got %q", port)
+		}
+
+	case "regexp":
+		_, err := regexp.Compile(s)
+		if err != nil {
+			msg = "must be a valid regular expression: " + err.Error()
+		}
+
+	case "url":
+		u, err := url.Parse(s)
+		if err != nil || !u.IsAbs() {
+			msg = fmt.Sprintf("must be an absolute URL, got %q", s)
+		}
+	}
+
+	if msg == "" {
+		return nil
+	}
+
+	return ValidationErrors{{Message: msg}}
+}
+
+// checkBound checks a min or max rule against the length of strings and
+// collections, or the value of numbers and durations.
+func checkBound(v reflect.Value, rule, param string) string {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+
+		v = v.Elem()
+	}
+
+	var value, bound float64
+	var err error
+	what := "value"
+
+	switch {
+	case isCollection(v):
+		what = "length"
+		value = float64(v.Len())
+		bound, err = strconv.ParseFloat(param, 64)
+
+	case v.Type() == reflect.TypeOf(time.Duration(0)):
+		var d time.Duration
+		d, err = time.ParseDuration(param)
+		value, bound = float64(v.Int()), float64(d)
+
+	case v.CanInt():
+		value = float64(v.Int())
+		bound, err = strconv.ParseFloat(param, 64)
+
+	case v.CanUint():
+		value = float64(v.Uint())
+		bound, err = strconv.ParseFloat(param, 64)
+
+	case v.CanFloat():
+		value = v.Float()
+		bound, err = strconv.ParseFloat(param, 64)
+
+	default:
+		return fmt.Sprintf("rule %q cannot be applied to %s", rule, v.Type())
+	}
+
+	if err != nil {
+		return fmt.Sprintf("invalid %s parameter %q", rule, param)
+	}
+
+	if rule == "min" && value < bound {
+		return fmt.Sprintf("%s must be at least %s", what, param)
+	}
+
+	if rule == "max" && value > bound {
+		return fmt.Sprintf("%s must be at most %s", what, param)
+	}
+
+	return ""
+}
+
+// isCollection returns whether v has a length.
+func isCollection(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	}
+
+	return false
 }
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type validationStream struct {
+	Name    string   `yaml:"name" validate:"required"`
+	Subject string   `yaml:"subject"`
+	Actions []string `yaml:"actions" validate:"oneof=start stop"`
+}
+
+// Validate checks rules spanning multiple fields.
+func (s *validationStream) Validate() error {
+	if s.Subject == "" {
+		return &ValidationError{Field: "subject", Message: "is required for " + s.Name}
+	}
+
+	return nil
+}
+
+type validationConfig struct {
+	Addr     string                       `yaml:"addr" validate:"hostport"`
+	URL      string                       `yaml:"url" validate:"url"`
+	Pattern  string                       `yaml:"pattern" validate:"regexp"`
+	Level    string                       `yaml:"level" validate:"oneof=debug info"`
+	Retries  int                          `yaml:"retries" validate:"min=1,max=10"`
+	Timeout  time.Duration                `yaml:"timeout" validate:"min=1ms"`
+	Servers  []string                     `yaml:"servers" validate:"required,min=1,hostport"`
+	Streams  []*validationStream          `yaml:"streams" validate:"max=1"`
+	Named    map[string]*validationStream `yaml:"named"`
+	Optional *validationStream            `yaml:"optional"`
+}
+
+func TestValidate(t *testing.T) {
+	valid := &validationConfig{
+		Addr:    ":8080",
+		URL:     "https://example.com",
+		Pattern: "^a",
+		Level:   "info",
+		Retries: 1,
+		Timeout: time.Second,
+		Servers: []string{"localhost:4222"},
+		Streams: []*validationStream{{Name: "a", Subject: "a.>", Actions: []string{"start"}}},
+	}
+
+	err := Validate(valid)
+	if err != nil {
+		t.Fatalf("err = %v, want valid", err)
+	}
+
+	invalid := &validationConfig{
+		Addr:    "localhost",
+		URL:     "/path",
+		Pattern: "(",
+		Level:   "trace",
+		Retries: 11,
+		Timeout: time.Microsecond,
+		Streams: []*validationStream{
+			{Name: "a", Subject: "a"},
+			{Subject: "b", Actions: []string{"start", "kill"}},
+		},
+		Named: map[string]*validationStream{"x": {Name: "x"}},
+	}
+
+	err = Validate(invalid)
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("err = %v, want ValidationErrors", err)
+	}
+
+	want := map[string]string{
+		"addr":                  `must be a host:port address, got "localhost"`,
+		"url":                   `must be an absolute URL, got "/path"`,
+		"pattern":               "must be a valid regular expression: error parsing regexp: missing closing ): `(`",
+		"level":                 `must be one of debug, info, got "trace"`,
+		"retries":               "value must be at most 10",
+		"timeout":               "value must be at least 1ms",
+		"servers":               "is required",
+		"streams":               "length must be at most 1",
+		"streams[1].name":       "is required",
+		"streams[1].actions[1]": `must be one of start, stop, got "kill"`,
+		"named.x.subject":       "is required for x",
+	}
+
+	got := map[string]string{}
+	for _, ve := range errs {
+		got[ve.Field] = ve.Message
+	}
+
+	for field, msg := range want {
+		if got[field] != msg {
+			t.Errorf("%s: %q, want %q", field, got[field], msg)
+		}
+	}
+
+	if len(errs) != len(want) {
+		t.Errorf("errors = %v, want %d", err, len(want))
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	type config struct {
+		Port    string `yaml:"port" validate:"hostport"`
+		Unknown string `yaml:"unknown" validate:"email"`
+		Count   int    `yaml:"count" validate:"oneof=1 2"`
+	}
+
+	err := Validate(&config{Port: "localhost:http", Unknown: "a", Count: 1})
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("err = %v, want 3 errors", err)
+	}
+
+	want := []string{
+		`ValidationError:port: must have a valid port, got "http"`,
+		`ValidationError:unknown: unknown validation rule "email"`,
+		`ValidationError:count: rule "oneof" cannot be applied to int`,
+	}
+
+	for i, ve := range errs {
+		if ve.Error() != want[i] {
+			t.Errorf("errors[%d] = %s, want %s", i, ve, want[i])
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// DefaultWatchInterval is how often a Watcher checks files for changes if no
+// interval is configured.
+const DefaultWatchInterval = 5 * time.Second
+
+// Watcher polls configuration files for changes to their size or modification
+// time, which works for files replaced by editors, configuration management
+// and Kubernetes ConfigMap symlink swaps alike.
+type Watcher struct {
+	// Paths are the files to watch, a file that does not exist is considered
+	// changed once it is created. Directories are watched for files being
+	// added or removed, as well as changes to the files they contain.
+	Paths []string
+
+	// Interval optionally overrides DefaultWatchInterval.
+	Interval time.Duration
+}
+
+// fileState is the subset of file information used to detect changes.
+type fileState struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+// Watch calls changed each time any of the watched files change, until the
+// context is canceled. Multiple files changing within one interval result in a
+// single call.
+func (w *Watcher) Watch(ctx context.Context, changed func()) {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	last := w.stat()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			current := w.stat()
+
+			for path, state := range current {
+				if last[path] != state {
+					changed()
+					break
+				}
+			}
+
+			last = current
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (w *Watcher) stat() map[string]fileState {
+	states := map[string]fileState{}
+
+	for _, path := range w.Paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			states[path] = fileState{}
+			continue
+		}
+
+		states[path] = fileState{
+			exists:  true,
+			size:    fi.Size(),
+			modTime: fi.ModTime(),
+		}
+
+		if fi.IsDir() {
+			files, err := expandPaths([]string{path})
+			if err != nil {
+				continue
+			}
+
+			for k, v := range (&Watcher{Paths: files}).stat() {
+				states[k] = v
+			}
+		}
+	}
+
+	return states
+}
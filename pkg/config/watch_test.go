@@ -0,0 +1,99 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// watch runs w until the test ends, returning the channel signalled each time
+// the watched files change.
+func watch(t *testing.T, w *Watcher) chan struct{} {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	changed := make(chan struct{}, 10)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		w.Watch(ctx, func() { changed <- struct{}{} })
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return changed
+}
+
+// assertChanged fails the test unless changed is signalled once.
+func assertChanged(t *testing.T, changed chan struct{}, what string) {
+	t.Helper()
+
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no change after %s", what)
+	}
+
+	select {
+	case <-changed:
+		t.Fatalf("changed more than once after %s", what)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	confd := filepath.Join(dir, "conf.d")
+
+	err := os.Mkdir(confd, 0o700)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := writeTestFile(t, dir, "config.yml", "a: 1\n")
+	missing := filepath.Join(dir, "local.yml")
+
+	changed := watch(t, &Watcher{Paths: []string{path, missing, confd}, Interval: 10 * time.Millisecond})
+
+	select {
+	case <-changed:
+		t.Fatal("changed without changes")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	writeTestFile(t, dir, "config.yml", "a: 12\n")
+	assertChanged(t, changed, "modifying a file")
+
+	writeTestFile(t, dir, "local.yml", "b: 1\n")
+	assertChanged(t, changed, "creating a missing file")
+
+	writeTestFile(t, confd, "10-a.yml", "c: 1\n")
+	assertChanged(t, changed, "adding a file to a directory")
+
+	writeTestFile(t, confd, "10-a.yml", "c: 12\n")
+	assertChanged(t, changed, "modifying a file in a directory")
+
+	err = os.Remove(filepath.Join(confd, "10-a.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertChanged(t, changed, "removing a file from a directory")
+
+	// replacing a file by renaming another over it, as editors do.
+	target := writeTestFile(t, dir, "config.yml.new", "a: 123\n")
+
+	err = os.Rename(target, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertChanged(t, changed, "replacing a file")
+}
@@ -0,0 +1,83 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+)
+
+// contextKey is the type of the keys of values stored in contexts by this
+// package.
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	attrsKey
+)
+
+// NewContext returns a copy of ctx carrying the logger, returned by
+// FromContext.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// With returns a copy of ctx carrying attrs, in addition to the attributes it
+// already carries, which are added to the logger returned by FromContext.
+// Attributes replace those already carried with the same key, such as the
+// task of a nested task.
+func With(ctx context.Context, attrs ...slog.Attr) context.Context {
+	if len(attrs) == 0 {
+		return ctx
+	}
+
+	existing := Attrs(ctx)
+
+	// existing is never modified in place, as it may be shared by other
+	// contexts.
+	combined := make([]slog.Attr, 0, len(existing)+len(attrs))
+
+	for _, a := range existing {
+		if !slices.ContainsFunc(attrs, func(b slog.Attr) bool { return b.Key == a.Key }) {
+			combined = append(combined, a)
+		}
+	}
+
+	for i, a := range attrs {
+		if !slices.ContainsFunc(attrs[i+1:], func(b slog.Attr) bool { return b.Key == a.Key }) {
+			combined = append(combined, a)
+		}
+	}
+
+	return context.WithValue(ctx, attrsKey, combined)
+}
+
+// Attrs returns the attributes carried by ctx, added by With.
+func Attrs(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(attrsKey).([]slog.Attr)
+	return attrs
+}
+
+// FromContext returns the logger carried by ctx, or slog.Default if it
+// carries none, with the attributes carried by ctx, such as the task or HTTP
+// request the context belongs to. Any code given a context can therefore log
+// with the attributes of its callers:
+//
+//	log.FromContext(ctx).Info("service started", slog.String("unit", unit))
+func FromContext(ctx context.Context) *slog.Logger {
+	logger, ok := ctx.Value(loggerKey).(*slog.Logger)
+	if !ok {
+		logger = slog.Default()
+	}
+
+	attrs := Attrs(ctx)
+	if len(attrs) == 0 {
+		return logger
+	}
+
+	args := make([]any, len(attrs))
+	for i, a := range attrs {
+		args[i] = a
+	}
+
+	return logger.With(args...)
+}
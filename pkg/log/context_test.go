@@ -0,0 +1,77 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestWith(t *testing.T) {
+	ctx := With(context.Background(), slog.String("task", "parent"), slog.String("request_id", "1"))
+	child := With(ctx, slog.String("task", "child"), slog.String("unit", "a"))
+
+	want := []string{"request_id=1", "task=child", "unit=a"}
+
+	got := Attrs(child)
+	if len(got) != len(want) {
+		t.Fatalf("attrs = %v, want %v", got, want)
+	}
+
+	for i, a := range got {
+		if a.String() != want[i] {
+			t.Errorf("attrs[%d] = %s, want %s", i, a, want[i])
+		}
+	}
+
+	// the attributes of the parent context are unchanged.
+	if a := Attrs(ctx); len(a) != 2 || a[0].String() != "task=parent" {
+		t.Errorf("parent attrs = %v, want unchanged", a)
+	}
+
+	if With(ctx) != ctx {
+		t.Error("want the same context without attributes")
+	}
+}
+
+func TestWithDuplicateAttrs(t *testing.T) {
+	ctx := With(context.Background(), slog.String("task", "a"), slog.String("task", "b"))
+
+	if a := Attrs(ctx); len(a) != 1 || a[0].String() != "task=b" {
+		t.Errorf("attrs = %v, want the last task", a)
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(buf, nil))
+
+	ctx := NewContext(context.Background(), logger)
+	ctx = With(ctx, slog.String("task", "parent"))
+	ctx = With(ctx, slog.String("task", "child"))
+
+	FromContext(ctx).Info("hello", slog.String("unit", "a"))
+
+	// decoding into a map would hide duplicate keys, so they are counted.
+	if n := bytes.Count(buf.Bytes(), []byte(`"task"`)); n != 1 {
+		t.Fatalf("task written %d times: %s", n, buf)
+	}
+
+	record := map[string]any{}
+
+	err := json.Unmarshal(buf.Bytes(), &record)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if record["task"] != "child" || record["unit"] != "a" || record["msg"] != "hello" {
+		t.Errorf("record = %v, want the task of the context", record)
+	}
+}
+
+func TestFromContextDefault(t *testing.T) {
+	if FromContext(context.Background()) != slog.Default() {
+		t.Error("want slog.Default for a context without a logger")
+	}
+}
@@ -0,0 +1,163 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BackupTimeFormat is the format of the time appended to the path of rotated
+// log files.
+const BackupTimeFormat = "20060102T150405.000"
+
+// File is an io.WriteCloser appending to a log file, which is rotated when it
+// would exceed a size or has been open longer than an age. Rotated files are
+// renamed with the time of rotation appended to their path, such as
+// service.log.20230102T150405.000, and the oldest are removed.
+type File struct {
+	// Path is the path of the log file, created if it doesn't exist.
+	Path string
+
+	// MaxSize is the size in bytes the file is rotated before exceeding, or 0
+	// to never rotate by size.
+	MaxSize int64
+
+	// MaxAge is how long the file is written to before it is rotated, or 0 to
+	// never rotate by age.
+	MaxAge time.Duration
+
+	// MaxBackups is the number of rotated files that are kept, or 0 to keep
+	// every rotated file.
+	MaxBackups int
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+// Open opens the log file for writing, which is otherwise opened by the first
+// Write, so errors can be reported early.
+func (f *File) Open() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file != nil {
+		return nil
+	}
+
+	return f.open()
+}
+
+// Write appends p to the log file, rotating it first if necessary.
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		err := f.open()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	tooLarge := f.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.MaxSize
+	tooOld := f.MaxAge > 0 && time.Since(f.opened) >= f.MaxAge
+
+	if tooLarge || tooOld {
+		err := f.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+
+	return n, err
+}
+
+// Close closes the log file.
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+
+	err := f.file.Close()
+	f.file = nil
+
+	return err
+}
+
+// open opens or creates the log file for appending.
+func (f *File) open() error {
+	file, err := os.OpenFile(f.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("open log file: %w", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.opened = time.Now()
+
+	return nil
+}
+
+// rotate renames the log file, opens a new one and removes old backups.
+func (f *File) rotate() error {
+	err := f.file.Close()
+	if err != nil {
+		return fmt.Errorf("rotate log file: %w", err)
+	}
+
+	f.file = nil
+
+	err = os.Rename(f.Path, f.Path+"."+time.Now().Format(BackupTimeFormat))
+	if err != nil {
+		return fmt.Errorf("rotate log file: %w", err)
+	}
+
+	err = f.open()
+	if err != nil {
+		return err
+	}
+
+	if f.MaxBackups <= 0 {
+		return nil
+	}
+
+	matches, err := filepath.Glob(f.Path + ".*")
+	if err != nil {
+		return nil
+	}
+
+	backups := []string{}
+	for _, m := range matches {
+		_, err := time.Parse(BackupTimeFormat, m[len(f.Path)+1:])
+		if err == nil {
+			backups = append(backups, m)
+		}
+	}
+
+	// backups sort by the time they were rotated, oldest first.
+	sort.Strings(backups)
+
+	for len(backups) > f.MaxBackups {
+		os.Remove(backups[0])
+		backups = backups[1:]
+	}
+
+	return nil
+}
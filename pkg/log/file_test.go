@@ -0,0 +1,105 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// backups returns the rotated files of the log file at path.
+func backups(t *testing.T, path string) []string {
+	t.Helper()
+
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return matches
+}
+
+func TestFileRotatesBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "service.log")
+
+	// an existing file counts towards the size.
+	err := os.WriteFile(path, []byte("old\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &File{Path: path, MaxSize: 10, MaxBackups: 2}
+	defer f.Close()
+
+	err = f.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{"12345\n", "abcdef\n", "ghijkl\n", "mnopqr\n"} {
+		_, err := f.Write([]byte(line))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// backups are named by the time of rotation in milliseconds.
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil || string(b) != "mnopqr\n" {
+		t.Errorf("log file = %q, %v, want the last line", b, err)
+	}
+
+	rotated := backups(t, path)
+	if len(rotated) != 2 {
+		t.Fatalf("backups = %v, want the 2 most recent", rotated)
+	}
+
+	b, err = os.ReadFile(rotated[0])
+	if err != nil || string(b) != "abcdef\n" {
+		t.Errorf("oldest backup = %q, %v, want the second line", b, err)
+	}
+
+	if !strings.HasPrefix(rotated[0], path+".") {
+		t.Errorf("backup = %s, want the time appended to the path", rotated[0])
+	}
+}
+
+func TestFileRotatesByAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "service.log")
+
+	f := &File{Path: path, MaxAge: 20 * time.Millisecond}
+	defer f.Close()
+
+	_, err := f.Write([]byte("first\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	_, err = f.Write([]byte("second\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rotated := backups(t, path); len(rotated) != 1 {
+		t.Errorf("backups = %v, want 1", rotated)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil || string(b) != "second\n" {
+		t.Errorf("log file = %q, %v, want the second line", b, err)
+	}
+}
+
+func TestFileOpenFails(t *testing.T) {
+	f := &File{Path: filepath.Join(t.TempDir(), "missing", "service.log")}
+
+	err := f.Open()
+	if err == nil {
+		t.Error("want an error for a missing directory")
+	}
+}
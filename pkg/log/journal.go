@@ -0,0 +1,253 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// JournalSocket is the path of the socket of the systemd journal accepting
+// its native protocol.
+const JournalSocket = "/run/systemd/journal/socket"
+
+// JournalHandler is a slog.Handler that writes records to the systemd journal
+// using its native protocol, so attributes are stored as journal fields that
+// can be filtered by, such as with `journalctl SERVICE=foo TASK=Stats`.
+//
+// Attribute keys are uppercased with characters other than letters, digits and
+// underscores replaced by underscores, and prefixed by their groups, such that
+// the attribute "id" in the group "request" becomes REQUEST_ID. The message is
+// written as MESSAGE, the level as PRIORITY, and the source as CODE_FILE,
+// CODE_LINE and CODE_FUNC if enabled. Attributes whose field would be one of
+// these, or another field interpreted by the journal such as MESSAGE_ID or
+// SYSLOG_PID, are prefixed by ATTR_, such that "message" becomes ATTR_MESSAGE.
+type JournalHandler struct {
+	conn *net.UnixConn
+	addr *net.UnixAddr
+	opts slog.HandlerOptions
+
+	// fields are the fields added by WithAttrs, already serialized.
+	fields []byte
+
+	// groups are the groups opened by WithGroup.
+	groups []string
+}
+
+// NewJournalHandler returns a JournalHandler writing to the journal socket at
+// path, or JournalSocket if empty, with the options opts, which may be nil. An
+// error is returned if the socket doesn't exist, such as when not running
+// under systemd.
+func NewJournalHandler(path string, opts *slog.HandlerOptions) (*JournalHandler, error) {
+	if path == "" {
+		path = JournalSocket
+	}
+
+	_, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("journal socket: %w", err)
+	}
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("journal socket: %w", err)
+	}
+
+	h := &JournalHandler{
+		conn: conn,
+		addr: &net.UnixAddr{Name: path, Net: "unixgram"},
+	}
+
+	if opts != nil {
+		h.opts = *opts
+	}
+
+	// identify records by the name of the executable, as syslog does.
+	h.fields = journalField(nil, "SYSLOG_IDENTIFIER", filepath.Base(os.Args[0]))
+
+	return h, nil
+}
+
+func (h *JournalHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+
+	return level >= minLevel
+}
+
+func (h *JournalHandler) Handle(ctx context.Context, r slog.Record) error {
+	b := bytes.Clone(h.fields)
+
+	b = journalField(b, "MESSAGE", r.Message)
+	b = journalField(b, "PRIORITY", strconv.Itoa(priority(r.Level)))
+
+	if h.opts.AddSource && r.PC != 0 {
+		src := source(r)
+
+		b = journalField(b, "CODE_FILE", src.File)
+		b = journalField(b, "CODE_LINE", strconv.Itoa(src.Line))
+		b = journalField(b, "CODE_FUNC", src.Function)
+	}
+
+	r.Attrs(func(a slog.Attr) bool {
+		b = h.appendAttr(b, h.groups, a)
+		return true
+	})
+
+	_, _, err := h.conn.WriteMsgUnix(b, nil, h.addr)
+	if errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS) {
+		// records too large for a datagram are passed as a file.
+		err = sendJournalFile(h.conn, h.addr, b)
+	}
+
+	if err != nil {
+		return fmt.Errorf("journal: %w", err)
+	}
+
+	return nil
+}
+
+func (h *JournalHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := bytes.Clone(h.fields)
+	for _, a := range attrs {
+		fields = h.appendAttr(fields, h.groups, a)
+	}
+
+	h2 := *h
+	h2.fields = fields
+
+	return &h2
+}
+
+func (h *JournalHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	h2 := *h
+	h2.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+
+	return &h2
+}
+
+// Close closes the connection to the journal, which is shared by every
+// handler derived from h.
+func (h *JournalHandler) Close() error {
+	return h.conn.Close()
+}
+
+// appendAttr appends the attribute a within groups to b as a journal field,
+// flattening groups into the field name.
+func (h *JournalHandler) appendAttr(b []byte, groups []string, a slog.Attr) []byte {
+	a.Value = a.Value.Resolve()
+
+	if h.opts.ReplaceAttr != nil && a.Value.Kind() != slog.KindGroup {
+		a = h.opts.ReplaceAttr(groups, a)
+		a.Value = a.Value.Resolve()
+	}
+
+	if a.Equal(slog.Attr{}) {
+		return b
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		// an unnamed group is inlined into its parent.
+		if a.Key != "" {
+			groups = append(groups[:len(groups):len(groups)], a.Key)
+		}
+
+		for _, ga := range a.Value.Group() {
+			b = h.appendAttr(b, groups, ga)
+		}
+
+		return b
+	}
+
+	name := fieldName(append(groups[:len(groups):len(groups)], a.Key))
+	if name == "" {
+		return b
+	}
+
+	if reservedFields[name] {
+		name = "ATTR_" + name
+	}
+
+	return journalField(b, name, formatValue(a.Value))
+}
+
+// reservedFields are the fields written by a JournalHandler or interpreted by
+// the journal, which attributes can't be written as.
+var reservedFields = map[string]bool{
+	"MESSAGE":            true,
+	"MESSAGE_ID":         true,
+	"PRIORITY":           true,
+	"CODE_FILE":          true,
+	"CODE_LINE":          true,
+	"CODE_FUNC":          true,
+	"ERRNO":              true,
+	"INVOCATION_ID":      true,
+	"USER_INVOCATION_ID": true,
+	"SYSLOG_FACILITY":    true,
+	"SYSLOG_IDENTIFIER":  true,
+	"SYSLOG_PID":         true,
+	"SYSLOG_TIMESTAMP":   true,
+	"SYSLOG_RAW":         true,
+	"DOCUMENTATION":      true,
+	"TID":                true,
+}
+
+// fieldName returns the journal field name of an attribute key within groups,
+// or an empty string if it has no valid characters.
+func fieldName(keys []string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		}
+
+		return '_'
+	}, strings.Join(keys, "_"))
+
+	// fields beginning with an underscore are trusted fields set by the
+	// journal, and fields can't begin with a digit.
+	return strings.TrimLeft(name, "_0123456789")
+}
+
+// journalField appends the field name=value to b in the native protocol of
+// the journal, where values containing newlines are prefixed by their length.
+func journalField(b []byte, name, value string) []byte {
+	if !strings.Contains(value, "\n") {
+		return append(append(append(append(b, name...), '='), value...), '\n')
+	}
+
+	b = append(append(b, name...), '\n')
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(value)))
+
+	return append(append(b, value...), '\n')
+}
+
+// priority returns the syslog priority of a level.
+func priority(level slog.Level) int {
+	switch {
+	case level >= slog.LevelError:
+		return 3
+	case level >= slog.LevelWarn:
+		return 4
+	case level >= slog.LevelInfo:
+		return 6
+	}
+
+	return 7
+}
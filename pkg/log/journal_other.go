@@ -0,0 +1,13 @@
+//go:build !unix
+
+package log
+
+import (
+	"errors"
+	"net"
+)
+
+// sendJournalFile is unsupported without the journal.
+func sendJournalFile(conn *net.UnixConn, addr *net.UnixAddr, b []byte) error {
+	return errors.New("record too large")
+}
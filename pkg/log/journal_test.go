@@ -0,0 +1,271 @@
+//go:build unix
+
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// journal listens on a socket in place of the systemd journal.
+type journal struct {
+	conn *net.UnixConn
+	path string
+
+	// files is the number of entries passed as files.
+	files int
+}
+
+func newJournal(t *testing.T) *journal {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "socket")
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	return &journal{conn: conn, path: path}
+}
+
+// handler returns a JournalHandler writing to j, with the options opts.
+func (j *journal) handler(t *testing.T, opts *slog.HandlerOptions) *JournalHandler {
+	t.Helper()
+
+	h, err := NewJournalHandler(j.path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { h.Close() })
+
+	return h
+}
+
+// read returns the fields of the next entry written to j, either as a
+// datagram or as a file passed as a file descriptor.
+func (j *journal) read(t *testing.T) map[string]string {
+	t.Helper()
+
+	buf := make([]byte, 64*1024)
+	oob := make([]byte, syscall.CmsgSpace(4))
+
+	j.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	n, oobn, _, _, err := j.conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := buf[:n]
+
+	if oobn > 0 {
+		b = readFile(t, oob[:oobn])
+		j.files++
+	}
+
+	return parseEntry(t, b)
+}
+
+// readFile returns the contents of the file passed as a file descriptor in
+// the control message oob.
+func readFile(t *testing.T, oob []byte) []byte {
+	t.Helper()
+
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("control messages = %v, %v, want one", msgs, err)
+	}
+
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil || len(fds) != 1 {
+		t.Fatalf("file descriptors = %v, %v, want one", fds, err)
+	}
+
+	file := os.NewFile(uintptr(fds[0]), "journal")
+	defer file.Close()
+
+	// the file must be unlinked for the journal to accept it.
+	info, err := file.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if links := info.Sys().(*syscall.Stat_t).Nlink; links != 0 {
+		t.Errorf("file has %d links, want unlinked", links)
+	}
+
+	b, err := io.ReadAll(io.NewSectionReader(file, 0, info.Size()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+// parseEntry parses the fields of an entry in the native protocol of the
+// journal.
+func parseEntry(t *testing.T, b []byte) map[string]string {
+	t.Helper()
+
+	fields := map[string]string{}
+
+	for len(b) > 0 {
+		i := bytes.IndexAny(b, "=\n")
+		if i < 0 {
+			t.Fatalf("invalid entry: %q", b)
+		}
+
+		name := string(b[:i])
+
+		if b[i] == '=' {
+			b = b[i+1:]
+
+			end := bytes.IndexByte(b, '\n')
+			if end < 0 {
+				t.Fatalf("field %s isn't terminated", name)
+			}
+
+			fields[name] = string(b[:end])
+			b = b[end+1:]
+
+			continue
+		}
+
+		b = b[i+1:]
+		if len(b) < 8 {
+			t.Fatalf("field %s has no length", name)
+		}
+
+		size := binary.LittleEndian.Uint64(b)
+		b = b[8:]
+
+		if uint64(len(b)) < size+1 || b[size] != '\n' {
+			t.Fatalf("field %s has an invalid length %d", name, size)
+		}
+
+		fields[name] = string(b[:size])
+		b = b[size+1:]
+	}
+
+	return fields
+}
+
+func TestJournalHandler(t *testing.T) {
+	j := newJournal(t)
+	logger := slog.New(j.handler(t, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	logger.With("service", "foo").WithGroup("request").Warn("request failed",
+		"id", 1,
+		"user-agent", "curl",
+		slog.Group("", "inlined", true),
+		"_trusted", "x",
+		"duration", 1500*time.Millisecond)
+
+	fields := j.read(t)
+
+	want := map[string]string{
+		"SYSLOG_IDENTIFIER":  filepath.Base(os.Args[0]),
+		"MESSAGE":            "request failed",
+		"PRIORITY":           "4",
+		"SERVICE":            "foo",
+		"REQUEST_ID":         "1",
+		"REQUEST_USER_AGENT": "curl",
+		"REQUEST_INLINED":    "true",
+		"REQUEST__TRUSTED":   "x",
+		"REQUEST_DURATION":   "1.5s",
+	}
+
+	for name, value := range want {
+		if got, ok := fields[name]; !ok || got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+
+	if len(fields) != len(want) {
+		t.Errorf("fields = %v, want %v", fields, want)
+	}
+
+	logger.Debug("debug")
+
+	if got := j.read(t)["PRIORITY"]; got != "7" {
+		t.Errorf("PRIORITY = %s, want 7", got)
+	}
+}
+
+func TestJournalHandlerMultiline(t *testing.T) {
+	j := newJournal(t)
+	logger := slog.New(j.handler(t, nil))
+
+	logger.Error("first\nsecond", "stack", "a\nb=c\n")
+
+	fields := j.read(t)
+
+	if fields["MESSAGE"] != "first\nsecond" || fields["STACK"] != "a\nb=c\n" || fields["PRIORITY"] != "3" {
+		t.Errorf("fields = %q, want multi-line values", fields)
+	}
+}
+
+func TestJournalHandlerReservedFields(t *testing.T) {
+	j := newJournal(t)
+	logger := slog.New(j.handler(t, nil))
+
+	logger.Info("hello", "message", "attribute", "priority", 1, "syslog_identifier", "other", "code_line", 2)
+
+	fields := j.read(t)
+
+	want := map[string]string{
+		"MESSAGE":                "hello",
+		"PRIORITY":               "6",
+		"SYSLOG_IDENTIFIER":      filepath.Base(os.Args[0]),
+		"ATTR_MESSAGE":           "attribute",
+		"ATTR_PRIORITY":          "1",
+		"ATTR_SYSLOG_IDENTIFIER": "other",
+		"ATTR_CODE_LINE":         "2",
+	}
+
+	for name, value := range want {
+		if got := fields[name]; got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+}
+
+func TestJournalHandlerLargeRecord(t *testing.T) {
+	j := newJournal(t)
+	logger := slog.New(j.handler(t, nil))
+
+	// larger than the datagrams accepted by the socket.
+	value := strings.Repeat("x", 4*1024*1024)
+
+	logger.Info("large", "value", value)
+
+	fields := j.read(t)
+
+	if fields["MESSAGE"] != "large" || fields["VALUE"] != value {
+		t.Errorf("MESSAGE = %q, VALUE of %d bytes, want the large record", fields["MESSAGE"], len(fields["VALUE"]))
+	}
+
+	if j.files != 1 {
+		t.Error("want the record passed as a file")
+	}
+}
+
+func TestNewJournalHandlerMissingSocket(t *testing.T) {
+	_, err := NewJournalHandler(filepath.Join(t.TempDir(), "socket"), nil)
+	if err == nil {
+		t.Error("want an error without the journal socket")
+	}
+}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package log
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// sendJournalFile sends the serialized record b to the journal as a file
+// descriptor, for records too large for a datagram. The file must be unlinked
+// for the journal to accept it.
+func sendJournalFile(conn *net.UnixConn, addr *net.UnixAddr, b []byte) error {
+	dir := "/dev/shm"
+	if _, err := os.Stat(dir); err != nil {
+		dir = os.TempDir()
+	}
+
+	file, err := os.CreateTemp(dir, "journal.*")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	err = os.Remove(file.Name())
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(b)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = conn.WriteMsgUnix(nil, syscall.UnixRights(int(file.Fd())), addr)
+	return err
+}
@@ -0,0 +1,305 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ComponentKeys are the keys of the attributes identifying the component a
+// record was logged by, in order of precedence, such that the level of a task
+// overrides the level of the service it belongs to.
+var ComponentKeys = []string{"task", "service"}
+
+// Levels are the minimum levels of records, both by default and for specific
+// components, identified by the value of their ComponentKeys attributes. The
+// zero value uses slog.LevelInfo for every component, and Levels are safe to
+// change while in use.
+//
+// Levels can be temporarily overridden at runtime, such as to debug a single
+// component, which take precedence over the configured levels until they
+// expire.
+type Levels struct {
+	// Default is the level of components without their own level.
+	Default slog.LevelVar
+
+	mu         sync.RWMutex
+	components map[string]slog.Level
+
+	// overrides are keyed by component, or an empty string for the default
+	// level.
+	overrides map[string]*Override
+}
+
+// Override is a level temporarily overriding the configured level of a
+// component.
+type Override struct {
+	// Component is the component whose level is overridden, or empty for
+	// the default level.
+	Component string
+
+	// Level is the level of the component until the override expires.
+	Level slog.Level
+
+	// Expires is when the configured level is restored.
+	Expires time.Time
+}
+
+// Set sets the level of a component, such as the name of a task.
+func (l *Levels) Set(component string, level slog.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.components == nil {
+		l.components = map[string]slog.Level{}
+	}
+
+	l.components[component] = level
+}
+
+// Unset removes the level of a component, so it uses the default level.
+func (l *Levels) Unset(component string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.components, component)
+}
+
+// Replace replaces the levels of every component, such as when the
+// configuration is reloaded. Overrides are kept until they expire.
+func (l *Levels) Replace(components map[string]slog.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.components = maps.Clone(components)
+}
+
+// Components returns the configured levels of components.
+func (l *Levels) Components() map[string]slog.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return maps.Clone(l.components)
+}
+
+// Override overrides the level of a component, or the default level if the
+// component is empty, until the ttl has passed.
+func (l *Levels) Override(component string, level slog.Level, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	// expired overrides are otherwise only ignored.
+	maps.DeleteFunc(l.overrides, func(_ string, o *Override) bool {
+		return !now.Before(o.Expires)
+	})
+
+	if l.overrides == nil {
+		l.overrides = map[string]*Override{}
+	}
+
+	l.overrides[component] = &Override{
+		Component: component,
+		Level:     level,
+		Expires:   now.Add(ttl),
+	}
+}
+
+// Revert removes the override of a component, or the default level if the
+// component is empty, returning whether it was overridden.
+func (l *Levels) Revert(component string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	o, ok := l.overrides[component]
+	delete(l.overrides, component)
+
+	return ok && time.Now().Before(o.Expires)
+}
+
+// RevertAll removes every override.
+func (l *Levels) RevertAll() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.overrides = nil
+}
+
+// Overrides returns the overrides that haven't expired, sorted by component.
+func (l *Levels) Overrides() []*Override {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	now := time.Now()
+	overrides := []*Override{}
+
+	for _, o := range l.overrides {
+		if now.Before(o.Expires) {
+			overrides = append(overrides, &Override{Component: o.Component, Level: o.Level, Expires: o.Expires})
+		}
+	}
+
+	slices.SortFunc(overrides, func(a, b *Override) int {
+		return strings.Compare(a.Component, b.Component)
+	})
+
+	return overrides
+}
+
+// Level returns the level of the first of the components with a level, in
+// order of precedence, or the default level, where overrides take precedence
+// over the configured level of the same component.
+func (l *Levels) Level(components ...string) slog.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	now := time.Time{}
+	if len(l.overrides) > 0 {
+		now = time.Now()
+	}
+
+	for _, c := range components {
+		if c == "" {
+			continue
+		}
+
+		if o, ok := l.overrides[c]; ok && now.Before(o.Expires) {
+			return o.Level
+		}
+
+		if level, ok := l.components[c]; ok {
+			return level
+		}
+	}
+
+	if o, ok := l.overrides[""]; ok && now.Before(o.Expires) {
+		return o.Level
+	}
+
+	return l.Default.Level()
+}
+
+// min returns the lowest of the default level and the levels of components,
+// including overrides.
+func (l *Levels) min() slog.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	minLevel := l.Default.Level()
+	for _, level := range l.components {
+		minLevel = min(minLevel, level)
+	}
+
+	now := time.Now()
+	for _, o := range l.overrides {
+		if now.Before(o.Expires) {
+			minLevel = min(minLevel, o.Level)
+		}
+	}
+
+	return minLevel
+}
+
+// AllLevels is a slog.Leveler enabling every level, for use by a handler
+// wrapped by NewLevelHandler.
+var AllLevels slog.Leveler = slog.Level(math.MinInt)
+
+// LevelHandler is a slog.Handler that filters records by the Levels of the
+// component they were logged by.
+type LevelHandler struct {
+	handler slog.Handler
+	levels  *Levels
+
+	// components are the values of ComponentKeys added by WithAttrs.
+	components []string
+
+	// grouped is set once a group is opened, as attributes in groups don't
+	// identify components.
+	grouped bool
+}
+
+// NewLevelHandler returns a LevelHandler passing records enabled by levels to
+// the handler h, which should enable every level, see AllLevels.
+func NewLevelHandler(h slog.Handler, levels *Levels) *LevelHandler {
+	return &LevelHandler{
+		handler:    h,
+		levels:     levels,
+		components: make([]string, len(ComponentKeys)),
+	}
+}
+
+// LevelsOf returns the Levels of a LevelHandler, or nil if h isn't one.
+func LevelsOf(h slog.Handler) *Levels {
+	if lh, ok := h.(*LevelHandler); ok {
+		return lh.levels
+	}
+
+	return nil
+}
+
+func (h *LevelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	// the record may identify a component with higher precedence than
+	// those already known, which can only be checked when it is handled.
+	if h.components[0] == "" {
+		minLevel := min(h.levels.Level(h.components...), h.levels.min())
+		return level >= minLevel && h.handler.Enabled(ctx, level)
+	}
+
+	return level >= h.levels.Level(h.components...) && h.handler.Enabled(ctx, level)
+}
+
+func (h *LevelHandler) Handle(ctx context.Context, r slog.Record) error {
+	components := h.components
+
+	if components[0] == "" {
+		components = slices.Clone(components)
+
+		r.Attrs(func(a slog.Attr) bool {
+			if i := slices.Index(ComponentKeys, a.Key); i >= 0 && !h.grouped {
+				components[i] = a.Value.String()
+			}
+			return true
+		})
+	}
+
+	if r.Level < h.levels.Level(components...) {
+		return nil
+	}
+
+	return h.handler.Handle(ctx, r)
+}
+
+func (h *LevelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.handler = h.handler.WithAttrs(attrs)
+
+	if !h.grouped {
+		h2.components = slices.Clone(h.components)
+
+		for _, a := range attrs {
+			if i := slices.Index(ComponentKeys, a.Key); i >= 0 {
+				h2.components[i] = a.Value.String()
+			}
+		}
+	}
+
+	return &h2
+}
+
+func (h *LevelHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	h2 := *h
+	h2.handler = h.handler.WithGroup(name)
+	h2.grouped = true
+
+	return &h2
+}
@@ -0,0 +1,136 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// levelLogger returns a logger filtering records by levels, and the buffer
+// records are written to.
+func levelLogger(levels *Levels) (*slog.Logger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	h := slog.NewTextHandler(buf, &slog.HandlerOptions{Level: AllLevels})
+
+	return slog.New(NewLevelHandler(h, levels)), buf
+}
+
+func TestLevelHandlerComponents(t *testing.T) {
+	levels := &Levels{}
+	levels.Default.Set(slog.LevelWarn)
+	levels.Set("foo", slog.LevelInfo)
+	levels.Set("Stats", slog.LevelDebug)
+
+	logger, buf := levelLogger(levels)
+
+	logger.Info("default")
+	logger.Warn("default warning")
+
+	svc := logger.With("service", "foo")
+	svc.Debug("service debug")
+	svc.Info("service info")
+
+	// the level of a task overrides its service.
+	svc.With("task", "Stats").Debug("task debug")
+	svc.Debug("record task debug", "task", "Stats")
+
+	// attributes in groups don't identify components.
+	logger.WithGroup("g").Debug("grouped", "task", "Stats")
+
+	want := []string{"default warning", "service info", "task debug", "record task debug"}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("records = %q, want %v", lines, want)
+	}
+
+	for i, line := range lines {
+		if !strings.Contains(line, "msg=\""+want[i]+"\"") {
+			t.Errorf("records[%d] = %s, want %s", i, line, want[i])
+		}
+	}
+
+	// records of known tasks are disabled without being handled.
+	if logger.With("task", "Other").Enabled(context.Background(), slog.LevelInfo) {
+		t.Error("want info disabled for Other")
+	}
+}
+
+func TestLevelsReplace(t *testing.T) {
+	levels := &Levels{}
+	levels.Set("a", slog.LevelDebug)
+
+	levels.Replace(map[string]slog.Level{"b": slog.LevelError})
+
+	if levels.Level("a") != slog.LevelInfo || levels.Level("b") != slog.LevelError {
+		t.Errorf("components = %v, want replaced", levels.Components())
+	}
+
+	levels.Unset("b")
+
+	if levels.Level("b") != slog.LevelInfo {
+		t.Error("want b unset")
+	}
+
+	if LevelsOf(NewLevelHandler(slog.Default().Handler(), levels)) != levels || LevelsOf(slog.Default().Handler()) != nil {
+		t.Error("want the Levels of LevelHandlers only")
+	}
+}
+
+func TestLevelsOverride(t *testing.T) {
+	levels := &Levels{}
+	levels.Set("Stats", slog.LevelWarn)
+
+	// overrides take precedence over the configured levels.
+	levels.Override("Stats", slog.LevelDebug, time.Minute)
+	levels.Override("", slog.LevelError, time.Minute)
+
+	if levels.Level("Stats") != slog.LevelDebug || levels.Level("Other") != slog.LevelError {
+		t.Errorf("levels = %v, %v, want overridden", levels.Level("Stats"), levels.Level("Other"))
+	}
+
+	// the configured levels are kept, and restored once reverted.
+	levels.Replace(map[string]slog.Level{"Stats": slog.LevelInfo})
+
+	overrides := levels.Overrides()
+	if len(overrides) != 2 || overrides[0].Component != "" || overrides[1].Component != "Stats" {
+		t.Errorf("overrides = %v, want sorted by component", overrides)
+	}
+
+	if !levels.Revert("Stats") || levels.Revert("Stats") {
+		t.Error("want Stats reverted once")
+	}
+
+	if levels.Level("Stats") != slog.LevelInfo {
+		t.Errorf("Stats = %v, want the configured level", levels.Level("Stats"))
+	}
+
+	levels.RevertAll()
+
+	if levels.Level("Other") != slog.LevelInfo || len(levels.Overrides()) != 0 {
+		t.Error("want every override reverted")
+	}
+}
+
+func TestLevelsOverrideExpires(t *testing.T) {
+	levels := &Levels{}
+
+	logger, buf := levelLogger(levels)
+
+	levels.Override("", slog.LevelDebug, 20*time.Millisecond)
+	logger.Debug("overridden")
+
+	time.Sleep(30 * time.Millisecond)
+	logger.Debug("expired")
+
+	if got := buf.String(); !strings.Contains(got, "overridden") || strings.Contains(got, "expired") {
+		t.Errorf("records = %q, want debug enabled until the override expires", got)
+	}
+
+	if levels.Revert("") || len(levels.Overrides()) != 0 {
+		t.Error("want the expired override ignored")
+	}
+}
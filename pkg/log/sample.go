@@ -0,0 +1,176 @@
+package log
+
+import (
+	"context"
+	"hash/fnv"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SampleOptions configures a SampleHandler.
+type SampleOptions struct {
+	// First is the number of records with the same level, message and
+	// attributes passed on within each Interval.
+	First int
+
+	// Thereafter passes on every Thereafter-th record beyond First within
+	// each Interval, or none if 0.
+	Thereafter int
+
+	// Interval is how long records are counted for, and how often the number
+	// of dropped records is reported, which is a second if 0.
+	Interval time.Duration
+}
+
+// SampleHandler is a slog.Handler that limits how many repeated records are
+// passed to another handler, such as those logged once per event. Records are
+// repeated when they have the same level, message and attributes, including
+// those added by WithAttrs.
+//
+// The number of records dropped within an interval is reported as a warning
+// at the end of the interval.
+type SampleHandler struct {
+	handler slog.Handler
+	sampler *sampler
+
+	// seed is the hash of the attributes and groups added by WithAttrs and
+	// WithGroup.
+	seed uint64
+}
+
+// sampler counts the records of a SampleHandler and handlers derived from it.
+type sampler struct {
+	handler slog.Handler
+	opts    SampleOptions
+
+	mu      sync.Mutex
+	start   time.Time
+	counts  map[uint64]int
+	dropped int
+
+	// report reports the records dropped at the end of the interval, which
+	// is nil while no records have been dropped in the interval.
+	report *time.Timer
+}
+
+// NewSampleHandler returns a SampleHandler passing records to the handler h,
+// sampled according to opts.
+func NewSampleHandler(h slog.Handler, opts SampleOptions) *SampleHandler {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+
+	return &SampleHandler{
+		handler: h,
+		sampler: &sampler{
+			handler: h,
+			opts:    opts,
+			start:   time.Now(),
+			counts:  map[uint64]int{},
+		},
+	}
+}
+
+func (h *SampleHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *SampleHandler) Handle(ctx context.Context, r slog.Record) error {
+	hash := fnv.New64a()
+	hash.Write(strconv.AppendUint(nil, h.seed, 16))
+	hash.Write([]byte(r.Level.String()))
+	hash.Write([]byte(r.Message))
+
+	r.Attrs(func(a slog.Attr) bool {
+		hash.Write([]byte(a.String()))
+		return true
+	})
+
+	if !h.sampler.count(hash.Sum64()) {
+		return nil
+	}
+
+	return h.handler.Handle(ctx, r)
+}
+
+func (h *SampleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	hash := fnv.New64a()
+	hash.Write(strconv.AppendUint(nil, h.seed, 16))
+
+	for _, a := range attrs {
+		hash.Write([]byte(a.String()))
+	}
+
+	return &SampleHandler{
+		handler: h.handler.WithAttrs(attrs),
+		sampler: h.sampler,
+		seed:    hash.Sum64(),
+	}
+}
+
+func (h *SampleHandler) WithGroup(name string) slog.Handler {
+	hash := fnv.New64a()
+	hash.Write(strconv.AppendUint(nil, h.seed, 16))
+	hash.Write([]byte("group:" + name))
+
+	return &SampleHandler{
+		handler: h.handler.WithGroup(name),
+		sampler: h.sampler,
+		seed:    hash.Sum64(),
+	}
+}
+
+// count counts a record with the hash key, returning whether it is passed on.
+func (s *sampler) count(key uint64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	// the counts of an interval with dropped records are reset when they're
+	// reported.
+	if s.report == nil && now.Sub(s.start) >= s.opts.Interval {
+		s.start = now
+		s.counts = map[uint64]int{}
+	}
+
+	n := s.counts[key] + 1
+	s.counts[key] = n
+
+	if n <= s.opts.First || (s.opts.Thereafter > 0 && (n-s.opts.First)%s.opts.Thereafter == 0) {
+		return true
+	}
+
+	s.dropped++
+
+	if s.report == nil {
+		s.report = time.AfterFunc(s.start.Add(s.opts.Interval).Sub(now), s.flush)
+	}
+
+	return false
+}
+
+// flush reports the number of records dropped since the start of the
+// interval, and starts the next.
+func (s *sampler) flush() {
+	s.mu.Lock()
+
+	dropped, since := s.dropped, s.start
+
+	s.start = time.Now()
+	s.counts = map[uint64]int{}
+	s.dropped = 0
+	s.report = nil
+
+	s.mu.Unlock()
+
+	report := slog.NewRecord(time.Now(), slog.LevelWarn, "log records dropped by sampling", 0)
+	report.AddAttrs(
+		slog.Int("dropped", dropped),
+		slog.Time("since", since))
+
+	// there's no caller to return an error to.
+	_ = s.handler.Handle(context.Background(), report)
+}
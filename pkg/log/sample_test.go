@@ -0,0 +1,119 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordHandler is a slog.Handler recording the records it handles.
+type recordHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *recordHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *recordHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.records = append(h.records, r)
+
+	return nil
+}
+
+func (h *recordHandler) WithAttrs([]slog.Attr) slog.Handler {
+	return h
+}
+
+func (h *recordHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// messages returns the messages of the records handled, and the number of
+// records dropped by those reporting them.
+func (h *recordHandler) messages() (messages []string, dropped int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, r := range h.records {
+		messages = append(messages, r.Message)
+
+		r.Attrs(func(a slog.Attr) bool {
+			if a.Key == "dropped" {
+				dropped += a.Value.Int64()
+			}
+
+			return true
+		})
+	}
+
+	return messages, dropped
+}
+
+func TestSampleHandler(t *testing.T) {
+	h := &recordHandler{}
+	logger := slog.New(NewSampleHandler(h, SampleOptions{First: 2, Thereafter: 3, Interval: 50 * time.Millisecond}))
+
+	start := time.Now()
+
+	// the 1st, 2nd, 5th and 8th are passed on.
+	for i := 0; i < 10; i++ {
+		logger.Info("repeated")
+	}
+
+	// other records are counted separately.
+	logger.Info("other")
+	logger.Info("repeated", "n", 1)
+	logger.With("n", 1).Info("repeated")
+
+	messages, _ := h.messages()
+	if len(messages) != 7 {
+		t.Fatalf("records = %v, want 7", messages)
+	}
+
+	// the number dropped is reported at the end of the interval without
+	// further records.
+	deadline := time.Now().Add(5 * time.Second)
+	for len(messages) == 7 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		messages, _ = h.messages()
+	}
+
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("reported after %s, want the end of the interval", elapsed)
+	}
+
+	messages, dropped := h.messages()
+	if len(messages) != 8 || messages[7] != "log records dropped by sampling" || dropped != 6 {
+		t.Fatalf("records = %v, dropped %d, want 6 dropped reported", messages, dropped)
+	}
+
+	// the next interval starts with new counts.
+	logger.Info("repeated")
+
+	if messages, _ := h.messages(); len(messages) != 9 {
+		t.Errorf("records = %v, want the repeated record passed on", messages)
+	}
+}
+
+func TestSampleHandlerNoneDropped(t *testing.T) {
+	h := &recordHandler{}
+	logger := slog.New(NewSampleHandler(h, SampleOptions{First: 2, Interval: 10 * time.Millisecond}))
+
+	logger.Info("repeated")
+	logger.Info("repeated")
+
+	time.Sleep(30 * time.Millisecond)
+
+	logger.Info("repeated")
+
+	if messages, dropped := h.messages(); len(messages) != 3 || dropped != 0 {
+		t.Errorf("records = %v, dropped %d, want 3 passed on without a report", messages, dropped)
+	}
+}
@@ -0,0 +1,201 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// TextTimeFormat is the format of the time of records written by
+// TextHandler.
+const TextTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// TextHandler is a slog.Handler that writes records as human-readable lines,
+// being the time, level and message followed by attributes as key=value:
+//
+//	2023-01-02T15:04:05.000Z INFO  task starting... service=foo task=Stats
+//
+// Unlike slog.TextHandler, the time, level and message are not written as
+// attributes, which is easier to read in a terminal or journalctl. The
+// ReplaceAttr option is applied to attributes, but not the time, level or
+// message.
+type TextHandler struct {
+	w    io.Writer
+	mu   *sync.Mutex
+	opts slog.HandlerOptions
+
+	// attrs are the attributes added by WithAttrs, already formatted.
+	attrs []byte
+
+	// groups are the groups opened by WithGroup.
+	groups []string
+}
+
+// NewTextHandler returns a TextHandler writing to w with the options opts,
+// which may be nil.
+func NewTextHandler(w io.Writer, opts *slog.HandlerOptions) *TextHandler {
+	h := &TextHandler{w: w, mu: &sync.Mutex{}}
+	if opts != nil {
+		h.opts = *opts
+	}
+
+	return h
+}
+
+func (h *TextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+
+	return level >= minLevel
+}
+
+func (h *TextHandler) Handle(ctx context.Context, r slog.Record) error {
+	b := &bytes.Buffer{}
+
+	if !r.Time.IsZero() {
+		b.WriteString(r.Time.Format(TextTimeFormat))
+		b.WriteByte(' ')
+	}
+
+	fmt.Fprintf(b, "%-5s %s", r.Level, r.Message)
+	b.Write(h.attrs)
+
+	r.Attrs(func(a slog.Attr) bool {
+		h.appendAttr(b, h.groups, a)
+		return true
+	})
+
+	if h.opts.AddSource && r.PC != 0 {
+		h.appendAttr(b, nil, slog.Any(slog.SourceKey, source(r)))
+	}
+
+	b.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_, err := h.w.Write(b.Bytes())
+	return err
+}
+
+func (h *TextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	b := bytes.NewBuffer(bytes.Clone(h.attrs))
+	for _, a := range attrs {
+		h.appendAttr(b, h.groups, a)
+	}
+
+	h2 := *h
+	h2.attrs = b.Bytes()
+
+	return &h2
+}
+
+func (h *TextHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	h2 := *h
+	h2.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+
+	return &h2
+}
+
+// appendAttr writes the attribute a within groups to b as " key=value",
+// flattening groups into dotted keys.
+func (h *TextHandler) appendAttr(b *bytes.Buffer, groups []string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+
+	if h.opts.ReplaceAttr != nil && a.Value.Kind() != slog.KindGroup {
+		a = h.opts.ReplaceAttr(groups, a)
+		a.Value = a.Value.Resolve()
+	}
+
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return
+		}
+
+		// an unnamed group is inlined into its parent.
+		if a.Key != "" {
+			groups = append(groups[:len(groups):len(groups)], a.Key)
+		}
+
+		for _, ga := range attrs {
+			h.appendAttr(b, groups, ga)
+		}
+
+		return
+	}
+
+	b.WriteByte(' ')
+
+	for _, g := range groups {
+		b.WriteString(quote(g))
+		b.WriteByte('.')
+	}
+
+	b.WriteString(quote(a.Key))
+	b.WriteByte('=')
+	b.WriteString(quote(formatValue(a.Value)))
+}
+
+// formatValue returns the string representation of v.
+func formatValue(v slog.Value) string {
+	switch v.Kind() {
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+
+	case slog.KindAny:
+		if src, ok := v.Any().(*slog.Source); ok {
+			return src.File + ":" + strconv.Itoa(src.Line)
+		}
+
+		if err, ok := v.Any().(error); ok {
+			return err.Error()
+		}
+	}
+
+	return v.String()
+}
+
+// quote returns s quoted if it's empty or contains spaces, quotes, equals
+// signs or unprintable characters, so it can be read unambiguously.
+func quote(s string) string {
+	if s == "" {
+		return `""`
+	}
+
+	for _, r := range s {
+		if unicode.IsSpace(r) || r == '"' || r == '=' || !unicode.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+
+	return s
+}
+
+// source returns the location of the log statement of r.
+func source(r slog.Record) *slog.Source {
+	frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+
+	return &slog.Source{
+		Function: frame.Function,
+		File:     frame.File,
+		Line:     frame.Line,
+	}
+}
@@ -0,0 +1,61 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTextHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := slog.New(NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == "drop" {
+				return slog.Attr{}
+			}
+
+			return a
+		},
+	}))
+
+	logger = logger.With("service", "foo").WithGroup("request")
+	logger.Info("task starting...",
+		"id", 1,
+		"path", "/a b",
+		"empty", "",
+		"drop", true,
+		"err", errors.New("failed"),
+		"at", time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC),
+		slog.Group("user", "name", `"x"`),
+		slog.Group("", "inlined", 1),
+		slog.Group("none"))
+
+	logger.Debug("discarded")
+
+	line := buf.String()
+
+	_, after, ok := strings.Cut(line, " ")
+	if !ok {
+		t.Fatalf("line = %q, want the time first", line)
+	}
+
+	want := `INFO  task starting... service=foo request.id=1 request.path="/a b" request.empty="" ` +
+		`request.err=failed request.at=2023-01-02T15:04:05Z request.user.name="\"x\"" request.inlined=1` + "\n"
+	if after != want {
+		t.Errorf("line = %q, want %q", after, want)
+	}
+}
+
+func TestTextHandlerSource(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	slog.New(NewTextHandler(buf, &slog.HandlerOptions{AddSource: true, Level: slog.LevelDebug})).Debug("debug")
+
+	if line := buf.String(); !strings.Contains(line, "DEBUG debug source=") || !strings.Contains(line, "text_test.go:") {
+		t.Errorf("line = %q, want the debug record with its source", line)
+	}
+}
@@ -2,14 +2,21 @@ package api
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"io"
 	"log/slog"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/svalevka/go/pkg/encoding"
+	"github.com/svalevka/go/pkg/log"
 )
 
+// RequestIDHeader is the HTTP Header identifying requests, which is read from
+// requests if set by a proxy and written to responses.
+const RequestIDHeader = "X-Request-ID"
+
 // None is a placeholder Request Body used to identify requests that are not
 // expecting a request to have a body.
 type None struct{}
@@ -54,6 +61,11 @@ type App struct {
 	// information.
 	Logger *slog.Logger
 
+	// Principal optionally returns who made an authenticated request, which
+	// is added to the context of handlers as the principal attribute of
+	// log.FromContext.
+	Principal func(*http.Request) string
+
 	router chi.Router
 }
 
@@ -85,6 +97,7 @@ func (a *App) Route(path string, setup func(*App)) {
 			ErrorHandler: a.ErrorHandler,
 			Encoding:     a.Encoding,
 			Logger:       a.Logger,
+			Principal:    a.Principal,
 			router:       r,
 		})
 	})
@@ -172,6 +185,39 @@ func URLParam(ctx context.Context, key string) string {
 	return chi.URLParamFromCtx(ctx, key)
 }
 
+// RequestID returns the ID of a request from its RequestIDHeader, or a new
+// random ID if it isn't set or isn't a valid ID.
+func RequestID(r *http.Request) string {
+	id := r.Header.Get(RequestIDHeader)
+	if validRequestID(id) {
+		return id
+	}
+
+	b := make([]byte, 8)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// validRequestID returns whether a request ID given by a client is safe to
+// log, being up to 128 letters, digits, dashes, dots and underscores.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '.', c == '_':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
 // Get registers an HTTP Method GET request with the Application.
 func Get[REQ, RES any](app *App, path string, fn func(context.Context, *Request[REQ]) (*Response[RES], error)) {
 	app.router.Get(path, handle(app, fn))
@@ -199,6 +245,16 @@ func Delete[REQ, RES any](app *App, path string, fn func(context.Context, *Reque
 
 func handle[REQ, RES any](app *App, fn func(context.Context, *Request[REQ]) (*Response[RES], error)) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		id := RequestID(r)
+		w.Header().Set(RequestIDHeader, id)
+
+		attrs := []slog.Attr{slog.String("request_id", id)}
+		if app.Principal != nil {
+			attrs = append(attrs, slog.String("principal", app.Principal(r)))
+		}
+
+		r = r.WithContext(log.With(r.Context(), attrs...))
+
 		req := &Request[REQ]{
 			Request: r,
 			Body:    new(REQ),
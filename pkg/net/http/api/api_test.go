@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/svalevka/go/pkg/encoding"
+	"github.com/svalevka/go/pkg/log"
+)
+
+// attrsApp returns an App responding with the log attributes of requests.
+func attrsApp(principal func(*http.Request) string) *App {
+	app := New(&encoding.JSON{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	app.Principal = principal
+
+	Get(app, "/", func(ctx context.Context, r *Request[None]) (*Response[[]string], error) {
+		attrs := []string{}
+		for _, a := range log.Attrs(ctx) {
+			attrs = append(attrs, a.String())
+		}
+
+		return &Response[[]string]{Body: &attrs}, nil
+	})
+
+	return app
+}
+
+func TestRequestAttrs(t *testing.T) {
+	app := attrsApp(func(*http.Request) string { return "admin" })
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(RequestIDHeader, "abc-123")
+
+	w := httptest.NewRecorder()
+	app.router.ServeHTTP(w, r)
+
+	if id := w.Header().Get(RequestIDHeader); id != "abc-123" {
+		t.Errorf("request ID = %q, want the given ID", id)
+	}
+
+	if body := strings.TrimSpace(w.Body.String()); body != `["request_id=abc-123","principal=admin"]` {
+		t.Errorf("attrs = %s, want the request ID and principal", body)
+	}
+}
+
+func TestRequestIDGenerated(t *testing.T) {
+	app := attrsApp(nil)
+
+	for _, id := range []string{"", "a b", strings.Repeat("a", 129)} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(RequestIDHeader, id)
+
+		w := httptest.NewRecorder()
+		app.router.ServeHTTP(w, r)
+
+		got := w.Header().Get(RequestIDHeader)
+		if len(got) != 16 || !strings.Contains(w.Body.String(), `"request_id=`+got+`"`) {
+			t.Errorf("request ID %q = %q, %s, want a generated ID", id, got, w.Body)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/svalevka/go/pkg/config/common"
+	"github.com/svalevka/go/pkg/encoding"
+	"github.com/svalevka/go/pkg/log"
+	"github.com/svalevka/go/pkg/net/http/api"
+	"github.com/svalevka/go/pkg/tasks"
+)
+
+// DefaultLevelTTL is how long log levels changed at runtime last, unless
+// configured by common.Admin.
+const DefaultLevelTTL = 15 * time.Minute
+
+// addAdmin adds Tasks changing the log levels of the Runner's Logger at
+// runtime, in response to signals and requests to the admin server if it is
+// enabled by the configuration, see common.Admin.
+//
+// SIGUSR1 toggles debug logging for every component, and SIGUSR2 reverts all
+// levels changed at runtime to those configured.
+func addAdmin(rn *Runner, cfg any) {
+	levels := log.LevelsOf(rn.Logger.Handler())
+	if levels == nil {
+		return
+	}
+
+	admin := &common.Admin{LevelTTL: DefaultLevelTTL}
+	if ga, ok := cfg.(interface {
+		GetAdmin() *common.Admin
+	}); ok {
+		admin = ga.GetAdmin()
+	}
+
+	rn.Tasks.Add(&levelSignals{
+		levels: levels,
+		ttl:    admin.LevelTTL,
+		logger: rn.Logger.With(slog.String("task", "LogLevelSignals")),
+	})
+
+	if admin.Listen == "" {
+		return
+	}
+
+	a := &adminAPI{
+		levels: levels,
+		ttl:    admin.LevelTTL,
+		token:  admin.Token.Value(),
+		logger: rn.Logger.With(slog.String("task", "Admin")),
+	}
+
+	rn.Tasks.Add(&tasks.HTTPServer{
+		Name:    "Admin",
+		Addr:    admin.Listen,
+		Handler: a.handler(),
+	})
+}
+
+// LogLevels describes the log levels of a service.
+type LogLevels struct {
+	// Default is the configured level of components without their own
+	// level.
+	Default string `json:"default"`
+
+	// Components are the configured levels of components.
+	Components map[string]string `json:"components"`
+
+	// Overrides are the levels changed at runtime, which take precedence
+	// until they expire.
+	Overrides []*LogLevelOverride `json:"overrides"`
+}
+
+// LogLevelOverride describes a log level changed at runtime.
+type LogLevelOverride struct {
+	// Component is the task or service whose level is changed, or empty for
+	// the default level.
+	Component string `json:"component,omitempty"`
+
+	// Level is one of debug, info, warn or error.
+	Level string `json:"level"`
+
+	// TTL is how long the level lasts, such as 10m, where the configured
+	// common.Admin LevelTTL is the default and maximum.
+	TTL string `json:"ttl,omitempty"`
+
+	// Expires is when the configured level is restored.
+	Expires time.Time `json:"expires"`
+}
+
+// adminError is an error returned to clients of the admin server.
+type adminError struct {
+	Status  int    `json:"-"`
+	Message string `json:"error"`
+}
+
+func (e *adminError) Error() string {
+	return e.Message
+}
+
+func (e *adminError) StatusCode() int {
+	return e.Status
+}
+
+// adminAPI implements the JSON API of the admin server.
+type adminAPI struct {
+	levels *log.Levels
+	ttl    time.Duration
+	token  string
+	logger *slog.Logger
+}
+
+// handler returns the handler of the admin server, authenticating requests.
+func (a *adminAPI) handler() http.Handler {
+	r := chi.NewRouter()
+	r.Use(a.authenticate)
+
+	app := api.From(&encoding.JSON{}, a.logger, r)
+	app.Principal = func(*http.Request) string {
+		return "admin"
+	}
+
+	a.Routes(app)
+
+	return r
+}
+
+// Routes attaches the routes of the admin server to an api.App.
+func (a *adminAPI) Routes(app *api.App) {
+	app.ErrorHandler = func(err error) any {
+		var ae *adminError
+		var se *json.SyntaxError
+		var te *json.UnmarshalTypeError
+
+		switch {
+		case errors.As(err, &ae):
+			return ae
+
+		case errors.As(err, &se), errors.As(err, &te):
+			return &adminError{Status: http.StatusBadRequest, Message: "invalid request body: " + err.Error()}
+		}
+
+		a.logger.Error("an unexpected error occurred", slog.String("error", err.Error()))
+
+		return &adminError{Status: http.StatusInternalServerError, Message: "an unexpected error occurred, check the logs"}
+	}
+
+	app.NotFound(&adminError{Status: http.StatusNotFound, Message: "resource not found"})
+	app.MethodNotAllowed(&adminError{Status: http.StatusMethodNotAllowed, Message: "method not allowed for resource"})
+
+	api.Get(app, "/log/levels", a.GetLevels)
+	api.Put(app, "/log/levels", a.SetLevel)
+	api.Delete(app, "/log/levels", a.RevertLevels)
+}
+
+// authenticate is HTTP middleware rejecting requests without the bearer token
+// of the admin server.
+func (a *adminAPI) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GetLevels returns the configured log levels and those changed at runtime.
+func (a *adminAPI) GetLevels(ctx context.Context, req *api.Request[api.None]) (*api.Response[LogLevels], error) {
+	res := &LogLevels{
+		Default:    levelName(a.levels.Default.Level()),
+		Components: map[string]string{},
+		Overrides:  []*LogLevelOverride{},
+	}
+
+	for component, level := range a.levels.Components() {
+		res.Components[component] = levelName(level)
+	}
+
+	for _, o := range a.levels.Overrides() {
+		res.Overrides = append(res.Overrides, &LogLevelOverride{
+			Component: o.Component,
+			Level:     levelName(o.Level),
+			TTL:       time.Until(o.Expires).Round(time.Second).String(),
+			Expires:   o.Expires,
+		})
+	}
+
+	return &api.Response[LogLevels]{Body: res}, nil
+}
+
+// SetLevel changes the log level of a component, or the default level, until
+// the TTL has passed.
+func (a *adminAPI) SetLevel(ctx context.Context, req *api.Request[LogLevelOverride]) (*api.Response[LogLevelOverride], error) {
+	var level slog.Level
+
+	err := level.UnmarshalText([]byte(req.Body.Level))
+	if err != nil {
+		return nil, &adminError{Status: http.StatusBadRequest, Message: "invalid level, expected one of debug, info, warn or error"}
+	}
+
+	ttl := a.ttl
+	if req.Body.TTL != "" {
+		ttl, err = time.ParseDuration(req.Body.TTL)
+		if err != nil || ttl <= 0 || ttl > a.ttl {
+			return nil, &adminError{Status: http.StatusBadRequest, Message: "invalid ttl, expected a duration up to " + a.ttl.String()}
+		}
+	}
+
+	a.levels.Override(req.Body.Component, level, ttl)
+
+	log.FromContext(ctx).Info("log level changed",
+		slog.String("component", req.Body.Component),
+		slog.String("level", levelName(level)),
+		slog.Duration("ttl", ttl))
+
+	return &api.Response[LogLevelOverride]{
+		Body: &LogLevelOverride{
+			Component: req.Body.Component,
+			Level:     levelName(level),
+			TTL:       ttl.String(),
+			Expires:   time.Now().Add(ttl),
+		},
+	}, nil
+}
+
+// RevertLevels reverts every log level changed at runtime.
+func (a *adminAPI) RevertLevels(ctx context.Context, req *api.Request[api.None]) (*api.Response[api.None], error) {
+	a.levels.RevertAll()
+	log.FromContext(ctx).Info("log levels reverted")
+
+	return &api.Response[api.None]{
+		StatusCode: http.StatusNoContent,
+	}, nil
+}
+
+// levelName returns the lowercase name of a level, as it is configured.
+func levelName(level slog.Level) string {
+	return strings.ToLower(level.String())
+}
+
+// levelSignals is a Task changing log levels when the process receives
+// SIGUSR1 or SIGUSR2, see addAdmin.
+type levelSignals struct {
+	levels *log.Levels
+	ttl    time.Duration
+	logger *slog.Logger
+}
+
+func (s *levelSignals) TaskName() string {
+	return "LogLevelSignals"
+}
+
+func (s *levelSignals) RunTask(ctx context.Context) error {
+	// the signals are unavailable on some platforms.
+	if debugSignal == nil {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, debugSignal, revertSignal)
+	defer signal.Stop(sig)
+
+	for {
+		select {
+		case received := <-sig:
+			if received == revertSignal {
+				s.levels.RevertAll()
+				s.logger.Info("log levels reverted")
+				continue
+			}
+
+			if s.levels.Revert("") {
+				s.logger.Info("debug logging disabled")
+				continue
+			}
+
+			s.levels.Override("", slog.LevelDebug, s.ttl)
+			s.logger.Info("debug logging enabled", slog.Duration("ttl", s.ttl))
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/svalevka/go/pkg/log"
+)
+
+// adminServer serves the admin API changing levels until the test ends.
+func adminServer(t *testing.T, levels *log.Levels) *httptest.Server {
+	t.Helper()
+
+	a := &adminAPI{
+		levels: levels,
+		ttl:    time.Hour,
+		token:  "secret",
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	s := httptest.NewServer(a.handler())
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// request makes a request to the admin server s with the token, returning the
+// status and body of the response.
+func request(t *testing.T, s *httptest.Server, method, token, body string) (int, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, s.URL+"/log/levels", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp.StatusCode, string(b)
+}
+
+func TestAdminLevels(t *testing.T) {
+	levels := &log.Levels{}
+	levels.Default.Set(slog.LevelWarn)
+	levels.Set("Stats", slog.LevelError)
+
+	s := adminServer(t, levels)
+
+	status, body := request(t, s, http.MethodPut, "secret", `{"component": "Stats", "level": "debug", "ttl": "5m"}`)
+	if status != http.StatusOK {
+		t.Fatalf("PUT = %d %s, want 200", status, body)
+	}
+
+	if levels.Level("Stats") != slog.LevelDebug {
+		t.Errorf("Stats = %v, want overridden", levels.Level("Stats"))
+	}
+
+	status, body = request(t, s, http.MethodGet, "secret", "")
+	if status != http.StatusOK {
+		t.Fatalf("GET = %d %s, want 200", status, body)
+	}
+
+	res := &LogLevels{}
+
+	err := json.Unmarshal([]byte(body), res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Default != "warn" || res.Components["Stats"] != "error" || len(res.Overrides) != 1 {
+		t.Fatalf("levels = %s, want the configured levels and the override", body)
+	}
+
+	if o := res.Overrides[0]; o.Component != "Stats" || o.Level != "debug" || o.TTL != "5m0s" {
+		t.Errorf("override = %+v, want Stats at debug for 5m", o)
+	}
+
+	status, body = request(t, s, http.MethodDelete, "secret", "")
+	if status != http.StatusNoContent {
+		t.Fatalf("DELETE = %d %s, want 204", status, body)
+	}
+
+	if levels.Level("Stats") != slog.LevelError {
+		t.Errorf("Stats = %v, want reverted", levels.Level("Stats"))
+	}
+}
+
+func TestAdminLevelsErrors(t *testing.T) {
+	s := adminServer(t, &log.Levels{})
+
+	tests := []struct {
+		method, token, body string
+		status              int
+		want                string
+	}{
+		{http.MethodGet, "wrong", "", http.StatusUnauthorized, "Unauthorized"},
+		{http.MethodGet, "", "", http.StatusUnauthorized, "Unauthorized"},
+		{http.MethodPut, "secret", `{"level": "verbose"}`, http.StatusBadRequest, "invalid level"},
+		{http.MethodPut, "secret", `{"level": "debug", "ttl": "2h"}`, http.StatusBadRequest, "invalid ttl, expected a duration up to 1h0m0s"},
+		{http.MethodPut, "secret", `{"level": "debug", "ttl": "-1m"}`, http.StatusBadRequest, "invalid ttl"},
+		{http.MethodPut, "secret", `{"level": 1}`, http.StatusBadRequest, "invalid request body"},
+		{http.MethodPost, "secret", "", http.StatusMethodNotAllowed, "method not allowed"},
+	}
+
+	for _, tt := range tests {
+		status, body := request(t, s, tt.method, tt.token, tt.body)
+		if status != tt.status || !strings.Contains(body, tt.want) {
+			t.Errorf("%s %q = %d %s, want %d %s", tt.method, tt.body, status, body, tt.status, tt.want)
+		}
+	}
+}
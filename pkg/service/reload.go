@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/svalevka/go/pkg/config"
+	"github.com/svalevka/go/pkg/log"
+)
+
+// OnReload registers a callback invoked with a newly loaded configuration when
+// the configuration file changes or the process receives SIGHUP. The callback
+// is only invoked once the new configuration has been successfully decoded and
+// validated, otherwise the old configuration is kept and the failure logged.
+//
+// The callback is expected to apply the new configuration atomically, for
+// example by adding or removing Tasks from the Runner or swapping state, and
+// may return an error if it could not be applied.
+func OnReload[CONFIG any](rn *Runner, fn func(context.Context, *CONFIG) error) {
+	rn.reload = func(ctx context.Context, cfg any) error {
+		return fn(ctx, cfg.(*CONFIG))
+	}
+}
+
+// reloader is a Task that reloads the configuration of the service when the
+// configuration file changes or the process receives SIGHUP.
+type reloader struct {
+	// load returns a newly loaded configuration.
+	load func() (any, error)
+
+	// reload is the callback registered with OnReload.
+	reload func(context.Context, any) error
+
+	watcher *config.Watcher
+	logger  *slog.Logger
+}
+
+func (r *reloader) TaskName() string {
+	return "ConfigReloader"
+}
+
+func (r *reloader) RunTask(ctx context.Context) error {
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+
+	go r.watcher.Watch(ctx, notify)
+
+	for {
+		select {
+		case <-sighup:
+			r.logger.Info("config reload requested by SIGHUP")
+			r.apply(ctx)
+
+		case <-changed:
+			r.logger.Info("config file changed, reloading...")
+			r.apply(ctx)
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (r *reloader) apply(ctx context.Context) {
+	cfg, err := r.load()
+	if err != nil {
+		r.logger.Error("config reload failed, keeping previous config", slog.String("error", err.Error()))
+		return
+	}
+
+	err = r.reload(ctx, cfg)
+	if err != nil {
+		r.logger.Error("config reload could not be applied", slog.String("error", err.Error()))
+		return
+	}
+
+	// the configured log levels are applied, while keeping any changed at
+	// runtime until they expire.
+	if al, ok := cfg.(interface {
+		ApplyLevels(*log.Levels)
+	}); ok {
+		if levels := log.LevelsOf(r.logger.Handler()); levels != nil {
+			al.ApplyLevels(levels)
+		}
+	}
+
+	r.logger.Info("config reloaded")
+	logConfig(r.logger, cfg)
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/svalevka/go/pkg/config"
+)
+
+type reloadConfig struct {
+	Value string `yaml:"value" validate:"required"`
+}
+
+// runReloader runs a reloader of the file at path until the test ends,
+// returning the channel given each configuration applied.
+func runReloader(t *testing.T, path string) chan *reloadConfig {
+	t.Helper()
+
+	applied := make(chan *reloadConfig, 10)
+
+	r := &reloader{
+		load: func() (any, error) {
+			cfg := &reloadConfig{}
+			err := config.FromFile(path, cfg)
+			return cfg, err
+		},
+		reload: func(ctx context.Context, cfg any) error {
+			applied <- cfg.(*reloadConfig)
+			return nil
+		},
+		watcher: &config.Watcher{Paths: []string{path}, Interval: 10 * time.Millisecond},
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.RunTask(ctx)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return applied
+}
+
+func writeConfig(t *testing.T, path, contents string) {
+	t.Helper()
+
+	err := os.WriteFile(path, []byte(contents), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloaderFileChanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	writeConfig(t, path, "value: a\n")
+
+	applied := runReloader(t, path)
+
+	// invalid configuration isn't applied.
+	writeConfig(t, path, "value: ''\n")
+
+	select {
+	case cfg := <-applied:
+		t.Fatalf("applied %+v, want the invalid configuration ignored", cfg)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	writeConfig(t, path, "value: bb\n")
+
+	select {
+	case cfg := <-applied:
+		if cfg.Value != "bb" {
+			t.Errorf("value = %q, want bb", cfg.Value)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("configuration not reloaded")
+	}
+}
+
+func TestReloaderNotApplied(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	r := &reloader{
+		load: func() (any, error) { return &reloadConfig{Value: "a"}, nil },
+		reload: func(ctx context.Context, cfg any) error {
+			return errors.New("failed")
+		},
+		logger: slog.New(slog.NewTextHandler(buf, nil)),
+	}
+
+	r.apply(context.Background())
+
+	if !strings.Contains(buf.String(), "config reload could not be applied") || strings.Contains(buf.String(), "config reloaded") {
+		t.Errorf("logs = %s, want the failure to apply logged", buf)
+	}
+}
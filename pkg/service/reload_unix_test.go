@@ -0,0 +1,45 @@
+//go:build unix
+
+package service
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReloaderSIGHUP(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	writeConfig(t, path, "value: a\n")
+
+	// SIGHUP would terminate the test until the reloader is notified of it.
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+
+	applied := runReloader(t, path)
+
+	deadline := time.After(5 * time.Second)
+
+	for {
+		err := syscall.Kill(os.Getpid(), syscall.SIGHUP)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case cfg := <-applied:
+			if cfg.Value != "a" {
+				t.Errorf("value = %q, want a", cfg.Value)
+			}
+
+			return
+		case <-time.After(20 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("configuration not reloaded")
+		}
+	}
+}
@@ -2,13 +2,21 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/daemon"
 
 	"github.com/svalevka/go/pkg/build"
 	"github.com/svalevka/go/pkg/config"
+	"github.com/svalevka/go/pkg/log"
 	"github.com/svalevka/go/pkg/tasks"
 )
 
@@ -18,40 +26,143 @@ type Runner struct {
 	// one must be defined.
 	Tasks *tasks.Runner
 
-	// Logger is a structures logger configured with service metadata.
+	// Logger is a structures logger configured with service metadata, which
+	// is also carried by the contexts given to setup and each Task, see
+	// log.FromContext.
 	Logger *slog.Logger
+
+	// reload is optionally registered by OnReload to apply a new
+	// configuration.
+	reload func(context.Context, any) error
 }
 
-// Run is a generic function that automatically reads a JSON or YAML
-// configuration file, then invokes a setup function for the Service, lastly
-// running the configured tasks.
+// Run is a generic function that automatically reads JSON or YAML
+// configuration files, merged in order and overlaid by environment variables
+// prefixed by the service name (see config.EnvPrefix) and command-line flags
+// named after the configuration fields (see config.NewFlags), then invokes a
+// setup function for the Service, lastly running the configured tasks. If the
+// Service registers a callback with OnReload, the configuration is reloaded
+// when the files change or the process receives SIGHUP.
+//
+// The tasks are stopped when the process receives SIGINT or SIGTERM, or when
+// any task fails, in which case the exit status is non-zero so a supervisor
+// such as systemd can restart the service. Once every task is ready, systemd
+// is notified, so units of Type=notify are only started once ready.
 func Run[CONFIG any](serviceName string, setup func(context.Context, *Runner, *CONFIG) error) int {
 	ctx := context.Background()
 
-	configFile := flag.String("config", "config.yml", "path to JSON or YAML configuration file")
+	configFiles := &configPaths{}
+	flag.Var(configFiles, "config", "path to JSON or YAML configuration file or conf.d directory, repeat to merge multiple (default config.yml)")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted, then exit")
+	printOrigins := flag.Bool("print-config-origins", false, "print the file, environment variable or flag each configuration value was loaded from, then exit")
+	printSchema := flag.Bool("print-config-schema", false, "print the JSON Schema of the configuration file, then exit")
+	printDefaults := flag.Bool("print-default-config", false, "print a commented configuration file populated with defaults, then exit")
+	migrateConfig := flag.Bool("migrate-config", false, "upgrade configuration files to the current version, keeping a backup of each, then exit")
+	configFlags := config.NewFlags(flag.CommandLine, new(CONFIG))
 	flag.Parse()
 
+	if *printDefaults {
+		bytes, err := config.RenderDefaults(new(CONFIG))
+		if err != nil {
+			return exitError(1, "Defaults: %s", err)
+		}
+
+		fmt.Print(string(bytes))
+		return 0
+	}
+
+	if *printSchema {
+		schema := config.JSONSchema(new(CONFIG))
+		schema.Title = serviceName
+
+		bytes, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			return exitError(1, "Schema: %s", err)
+		}
+
+		fmt.Println(string(bytes))
+		return 0
+	}
+
+	if len(*configFiles) == 0 {
+		*configFiles = configPaths{"config.yml"}
+	}
+
+	if *migrateConfig {
+		migrated, err := config.MigrateFiles(*configFiles, new(CONFIG))
+		for _, m := range migrated {
+			if m.Backup == "" {
+				fmt.Printf("%s: already version %d\n", m.Path, m.To)
+			} else {
+				fmt.Printf("%s: migrated version %d to %d, backup written to %s\n", m.Path, m.From, m.To, m.Backup)
+			}
+		}
+
+		if err != nil {
+			return exitError(2, "Migrate: %s", err)
+		}
+
+		return 0
+	}
+
+	loader := &config.Loader{
+		EnvPrefix: config.EnvPrefix(serviceName),
+		Flags:     configFlags,
+	}
+
 	cfg := new(CONFIG)
-	err := config.FromFile(*configFile, cfg)
+	origins, err := loader.FromFiles(*configFiles, cfg)
 	if err != nil {
 		return exitError(2, "Config: %s", err)
 	}
 
-	log := getLogger(cfg).With(slog.String("service", serviceName))
+	if *printOrigins {
+		fmt.Print(origins)
+		return 0
+	}
+
+	if *printConfig {
+		bytes, err := config.Dump(cfg, origins)
+		if err != nil {
+			return exitError(1, "Config: %s", err)
+		}
+
+		fmt.Print(string(bytes))
+		return 0
+	}
+
+	logger := getLogger(cfg).With(slog.String("service", serviceName))
+	ctx = log.NewContext(ctx, logger)
+
+	logConfig(logger, cfg)
 
 	rn := &Runner{
 		Tasks: &tasks.Runner{
 			TaskStarting: func(ts *tasks.TasksStatus) {
-				log.Info("task starting...", slog.String("task", ts.Name))
+				logger.Info("task starting...", slog.String("task", ts.Name))
 			},
 			TaskStopped: func(ts *tasks.TasksStatus) {
-				log.Info("task stopped", slog.String("task", ts.Name))
+				logger.Info("task stopped", slog.String("task", ts.Name))
 			},
 			TaskFailed: func(ts *tasks.TasksStatus, err error) {
-				log.Error("task failed", slog.String("task", ts.Name), slog.String("error", err.Error()))
+				logger.Error("task failed", slog.String("task", ts.Name), slog.String("error", err.Error()))
+			},
+			TaskRestarting: func(ts *tasks.TasksStatus, err error, delay time.Duration) {
+				attrs := []any{slog.String("task", ts.Name), slog.Int("restarts", ts.Restarts), slog.Duration("delay", delay)}
+				if err != nil {
+					attrs = append(attrs, slog.String("error", err.Error()))
+				}
+
+				logger.Warn("task restarting...", attrs...)
+			},
+			TasksReady: func() {
+				logger.Info("service ready")
+
+				// units of Type=notify are started once ready.
+				daemon.SdNotify(false, daemon.SdNotifyReady)
 			},
 		},
-		Logger: log,
+		Logger: logger,
 	}
 
 	err = setup(ctx, rn, cfg)
@@ -59,16 +170,79 @@ func Run[CONFIG any](serviceName string, setup func(context.Context, *Runner, *C
 		return exitError(1, "Setup: %s", err)
 	}
 
-	log.Info("service starting...", slog.String("revision", build.GetRevision(7)))
+	addAdmin(rn, cfg)
 
-	err = rn.Tasks.Run(ctx)
-	if err != nil {
-		return exitError(1, "Run: %s", err)
+	if rn.reload != nil {
+		rn.Tasks.Add(&reloader{
+			load: func() (any, error) {
+				cfg := new(CONFIG)
+				_, err := loader.FromFiles(*configFiles, cfg)
+				return cfg, err
+			},
+			reload:  rn.reload,
+			watcher: &config.Watcher{Paths: *configFiles},
+			logger:  logger.With(slog.String("task", "ConfigReloader")),
+		})
+	}
+
+	logger.Info("service starting...", slog.String("revision", build.GetRevision(7)))
+
+	return rn.run(ctx)
+}
+
+// run runs the Tasks until they stop, returning the exit status of the
+// service, which is non-zero unless they stopped by themselves or due to
+// SIGINT or SIGTERM.
+func (rn *Runner) run(ctx context.Context) int {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	stopping := context.AfterFunc(ctx, func() {
+		// a second signal terminates the process immediately.
+		stop()
+		rn.Logger.Info("service stopping...")
+		daemon.SdNotify(false, daemon.SdNotifyStopping)
+	})
+	defer stopping()
+
+	// the tasks return the error of the context when stopped by a signal,
+	// which is a clean exit, while any other error such as a failed task or
+	// missing dependency is not.
+	err := rn.Tasks.Run(ctx)
+	if err != nil && err != context.Canceled {
+		return exitError(1, "Run: %s", strings.ReplaceAll(err.Error(), "\n", "; "))
 	}
 
+	rn.Logger.Info("service stopped")
+
 	return 0
 }
 
+// logConfig writes the effective configuration to the debug log with secrets
+// redacted.
+func logConfig(log *slog.Logger, cfg any) {
+	redacted, err := config.Redact(cfg)
+	if err != nil {
+		log.Warn("could not redact config for logging", slog.String("error", err.Error()))
+		return
+	}
+
+	log.Debug("effective config", slog.Any("config", redacted))
+}
+
+// configPaths is a flag.Value collecting the paths of configuration files
+// given by repeating a flag.
+type configPaths []string
+
+func (c *configPaths) String() string {
+	return strings.Join(*c, ",")
+}
+
+func (c *configPaths) Set(v string) error {
+	*c = append(*c, v)
+	return nil
+}
+
 // getLogger returns a structured logger, either configured by the service
 // configuration using the GetLogger interface, or a default of JSON/DEBUG.
 func getLogger(cfg any) *slog.Logger {
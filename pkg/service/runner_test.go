@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/svalevka/go/pkg/tasks"
+)
+
+// funcTask is a Task running a function, which depends on deps.
+type funcTask struct {
+	name string
+	run  func(ctx context.Context) error
+	deps []tasks.Task
+}
+
+func (f *funcTask) TaskName() string {
+	return f.name
+}
+
+func (f *funcTask) DependsOn() []tasks.Task {
+	return f.deps
+}
+
+func (f *funcTask) RunTask(ctx context.Context) error {
+	return f.run(ctx)
+}
+
+// newRunner returns a Runner of the tasks, discarding its logs.
+func newRunner(tasksToRun ...tasks.Task) *Runner {
+	rn := &Runner{
+		Tasks:  &tasks.Runner{},
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	for _, task := range tasksToRun {
+		rn.Tasks.Add(task)
+	}
+
+	return rn
+}
+
+func TestRunnerExitStatus(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	expired, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+
+	blocking := &funcTask{name: "blocking", run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+
+	for _, tt := range []struct {
+		name string
+		ctx  context.Context
+		task tasks.Task
+		want int
+	}{
+		{"failed", context.Background(), &funcTask{name: "failed", run: func(context.Context) error { return errors.New("failed") }}, 1},
+		{"stopped", context.Background(), &funcTask{name: "stopped", run: func(context.Context) error { return nil }}, 0},
+		{"signaled", canceled, blocking, 0},
+
+		// errors other than a TaskError are also failures.
+		{"expired", expired, blocking, 1},
+	} {
+		status := newRunner(tt.task).run(tt.ctx)
+		if status != tt.want {
+			t.Errorf("%s: exit status = %d, want %d", tt.name, status, tt.want)
+		}
+	}
+}
+
+func TestRunnerExitStatusDependencies(t *testing.T) {
+	run := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	a := &funcTask{name: "a", run: run}
+	b := &funcTask{name: "b", run: run, deps: []tasks.Task{a}}
+	a.deps = []tasks.Task{b}
+
+	if status := newRunner(a, b).run(context.Background()); status != 1 {
+		t.Errorf("exit status = %d, want 1 for a dependency cycle", status)
+	}
+
+	c := &funcTask{name: "c", run: run, deps: []tasks.Task{&funcTask{name: "missing", run: run}}}
+
+	if status := newRunner(c).run(context.Background()); status != 1 {
+		t.Errorf("exit status = %d, want 1 for a missing dependency", status)
+	}
+}
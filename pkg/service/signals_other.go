@@ -0,0 +1,11 @@
+//go:build !unix
+
+package service
+
+import "os"
+
+// debugSignal and revertSignal are unavailable on this platform.
+var (
+	debugSignal  os.Signal
+	revertSignal os.Signal
+)
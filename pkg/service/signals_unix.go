@@ -0,0 +1,15 @@
+//go:build unix
+
+package service
+
+import (
+	"os"
+	"syscall"
+)
+
+// debugSignal toggles debug logging, and revertSignal reverts log levels
+// changed at runtime.
+var (
+	debugSignal  os.Signal = syscall.SIGUSR1
+	revertSignal os.Signal = syscall.SIGUSR2
+)
@@ -0,0 +1,78 @@
+//go:build unix
+
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/svalevka/go/pkg/log"
+)
+
+// signalUntil sends sig to the process until done returns true, as the
+// signal is missed until the task is notified of it.
+func signalUntil(t *testing.T, sig syscall.Signal, done func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for time.Now().Before(deadline) {
+		err := syscall.Kill(os.Getpid(), sig)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < 10; i++ {
+			if done() {
+				return
+			}
+
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	t.Fatalf("%v not handled", sig)
+}
+
+func TestLevelSignals(t *testing.T) {
+	// the signals would terminate the test until the task is notified of them.
+	sig := make(chan os.Signal, 10)
+	signal.Notify(sig, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(sig)
+
+	levels := &log.Levels{}
+	levels.Default.Set(slog.LevelWarn)
+
+	s := &levelSignals{
+		levels: levels,
+		ttl:    time.Minute,
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.RunTask(ctx)
+	}()
+
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// SIGUSR1 toggles debug logging.
+	signalUntil(t, syscall.SIGUSR1, func() bool { return levels.Level() == slog.LevelDebug })
+	signalUntil(t, syscall.SIGUSR1, func() bool { return levels.Level() == slog.LevelWarn })
+
+	// SIGUSR2 reverts every override.
+	levels.Override("Stats", slog.LevelDebug, time.Minute)
+	signalUntil(t, syscall.SIGUSR2, func() bool { return len(levels.Overrides()) == 0 })
+}
@@ -0,0 +1,18 @@
+// Code generated by docgen. DO NOT EDIT.
+
+package tasks
+
+import "github.com/svalevka/go/pkg/config"
+
+func init() {
+	config.RegisterDocs("github.com/svalevka/go/pkg/tasks", map[string]string{
+		"RestartPolicy":             "RestartPolicy configures whether a task is restarted when it stops, waiting an exponentially increasing delay between restarts.",
+		"RestartPolicy.Backoff":     "Backoff is the delay before the first restart, doubled by each consecutive restart, or zero to restart immediately.",
+		"RestartPolicy.Jitter":      "Jitter is the fraction of each delay randomly added or subtracted, so tasks failing together don't restart together.",
+		"RestartPolicy.MaxBackoff":  "MaxBackoff is the longest delay between restarts, or unlimited if zero.",
+		"RestartPolicy.MaxRestarts": "MaxRestarts is the number of restarts allowed within Window, after which the task fails, or unlimited if zero.",
+		"RestartPolicy.ResetAfter":  "ResetAfter is how long a task must run before it is considered stable, resetting its delay and restart count, or never if zero.",
+		"RestartPolicy.Restart":     "Restart is when the task is restarted, either never, on-failure when it returns an error, or always, including when it returns without one.",
+		"RestartPolicy.Window":      "Window is the period MaxRestarts are counted over, or the lifetime of the task if zero.",
+	})
+}
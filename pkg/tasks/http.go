@@ -4,7 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"time"
+
+	"github.com/svalevka/go/pkg/config"
+	"github.com/svalevka/go/pkg/config/common"
+	"github.com/svalevka/go/pkg/log"
 )
 
 // HTTPServer is a task that runs an HTTP(S) server.
@@ -12,30 +19,91 @@ type HTTPServer struct {
 	// Name identifies this Task in the TaskStatus calls from the Task Runner.
 	Name string
 
-	// Addr is the host:port where this HTTP server will listen.
+	// Addr is the host:port where this HTTP server will listen, if Config is
+	// not set.
 	Addr string
 
+	// Config optionally configures the server, including its address, TLS
+	// and timeouts, otherwise it listens on Addr with the default timeouts
+	// of common.HTTPServer.
+	Config *common.HTTPServer
+
 	// Handler is the HTTP callback used to serve new requests.
 	Handler http.Handler
+
+	// Dependencies are optionally the Tasks the Handler depends on, so the
+	// server only listens once they are ready, and stops before them.
+	Dependencies []Task
+}
+
+// DependsOn returns the Dependencies of the server.
+func (h *HTTPServer) DependsOn() []Task {
+	return h.Dependencies
+}
+
+// ReadyTimeout returns zero, as the server is ready once listening, or fails.
+func (h *HTTPServer) ReadyTimeout() time.Duration {
+	return 0
 }
 
 func (h *HTTPServer) TaskName() string {
-	return "HTTPServer(" + h.Name + "," + h.Addr + ")"
+	return "HTTPServer(" + h.Name + "," + h.config().Addr + ")"
 }
 
 func (h *HTTPServer) RunTask(ctx context.Context) error {
-	s := &http.Server{
-		Addr:    h.Addr,
-		Handler: h.Handler,
+	cfg := h.config()
+
+	s, err := cfg.Server(h.Handler)
+	if err != nil {
+		return err
+	}
+
+	// requests carry the values of the Task context, such as its logger, but
+	// aren't canceled with it so they complete during Shutdown.
+	s.BaseContext = func(net.Listener) context.Context {
+		return context.WithoutCancel(ctx)
+	}
+
+	// errors such as failed TLS handshakes are logged as warnings.
+	s.ErrorLog = slog.NewLogLogger(log.FromContext(ctx).Handler(), slog.LevelWarn)
+
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
 	}
 
+	// Tasks depending on the server are started once it accepts connections.
+	Ready(ctx)
+
+	// requests in progress are given ShutdownTimeout to complete once the
+	// Task is stopped, as its context is already canceled by then.
+	shutdown := make(chan struct{})
 	go func() {
+		defer close(shutdown)
+
 		<-ctx.Done()
-		s.Shutdown(ctx)
+
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cfg.ShutdownTimeout)
+		defer cancel()
+
+		err := s.Shutdown(shutdownCtx)
+		if err != nil {
+			s.Close()
+		}
 	}()
 
-	err := s.ListenAndServe()
+	if s.TLSConfig != nil {
+		// the certificates are loaded by the TLSConfig.
+		err = s.ServeTLS(ln, "", "")
+	} else {
+		err = s.Serve(ln)
+	}
+
 	if errors.Is(err, http.ErrServerClosed) {
+		// Serve returns once Shutdown is called, rather than once requests
+		// have completed.
+		<-shutdown
+
 		return ctx.Err()
 	} else if err != nil {
 		return fmt.Errorf("listen: %w", err)
@@ -43,3 +111,18 @@ func (h *HTTPServer) RunTask(ctx context.Context) error {
 
 	return nil
 }
+
+// config returns the configuration of the server, either Config or the
+// defaults with Addr.
+func (h *HTTPServer) config() *common.HTTPServer {
+	if h.Config != nil {
+		return h.Config
+	}
+
+	cfg := &common.HTTPServer{Addr: h.Addr}
+
+	// the default tags of HTTPServer are valid, so there is no error.
+	_ = config.SetDefaults(cfg)
+
+	return cfg
+}
@@ -0,0 +1,152 @@
+package tasks
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/svalevka/go/pkg/config/common"
+)
+
+// freeAddr returns a local address that is free to listen on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := ln.Addr().String()
+	ln.Close()
+
+	return addr
+}
+
+func TestHTTPServerDefaults(t *testing.T) {
+	cfg := (&HTTPServer{Addr: "localhost:8080"}).config()
+
+	if cfg.ReadHeaderTimeout != 10*time.Second || cfg.ShutdownTimeout != 30*time.Second {
+		t.Errorf("config = %+v, want the defaults of common.HTTPServer", cfg)
+	}
+}
+
+func TestHTTPServerShutdown(t *testing.T) {
+	addr := freeAddr(t)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	h := &HTTPServer{
+		Name: "test",
+		Config: &common.HTTPServer{
+			Addr:            addr,
+			ShutdownTimeout: 5 * time.Second,
+		},
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+			io.WriteString(w, "done")
+		}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- h.RunTask(ctx)
+	}()
+
+	body := make(chan string, 1)
+	go func() {
+		for {
+			resp, err := http.Get("http://" + addr)
+			if err != nil {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+
+			b, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			body <- string(b)
+
+			return
+		}
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case err := <-stopped:
+		t.Fatalf("stopped with %v before the request completed", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+
+	if b := <-body; b != "done" {
+		t.Errorf("body = %q, want the request to complete during shutdown", b)
+	}
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server didn't stop")
+	}
+}
+
+func TestHTTPServerShutdownTimeout(t *testing.T) {
+	addr := freeAddr(t)
+
+	started := make(chan struct{})
+
+	h := &HTTPServer{
+		Name: "test",
+		Config: &common.HTTPServer{
+			Addr:            addr,
+			ShutdownTimeout: 50 * time.Millisecond,
+		},
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-r.Context().Done()
+		}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- h.RunTask(ctx)
+	}()
+
+	go func() {
+		for {
+			resp, err := http.Get("http://" + addr)
+			if err == nil {
+				resp.Body.Close()
+				return
+			}
+
+			select {
+			case <-started:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server didn't stop after its shutdown timeout")
+	}
+}
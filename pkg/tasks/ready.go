@@ -0,0 +1,115 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ReadyTask is implemented by Tasks which signal when they are ready, such as
+// once listening or subscribed, by calling Ready with the context given to
+// RunTask. Tasks depending on them aren't started until then, while other
+// Tasks are ready once started.
+type ReadyTask interface {
+	Task
+
+	// ReadyTimeout returns how long the Task may take to become ready each
+	// time it is started, after which it fails, or zero to wait
+	// indefinitely.
+	ReadyTimeout() time.Duration
+}
+
+// DependentTask is implemented by Tasks which depend on other Tasks of the same
+// Runner, so they are started once those are ready, and stopped before them.
+type DependentTask interface {
+	Task
+
+	// DependsOn returns the Tasks the Task depends on, which must be added
+	// to the Runner before it.
+	DependsOn() []Task
+}
+
+// readyKey is the key of the function signaling a Task is ready in the
+// context given to RunTask.
+type readyKey struct{}
+
+// Ready signals the Task given ctx is ready, see ReadyTask. Calling it more
+// than once, or from Tasks that aren't a ReadyTask, has no effect.
+func Ready(ctx context.Context) {
+	ready, _ := ctx.Value(readyKey{}).(func())
+	if ready != nil {
+		ready()
+	}
+}
+
+// withReady returns a copy of ctx where Ready calls ready, or does nothing if
+// ready is nil.
+func withReady(ctx context.Context, ready func()) context.Context {
+	return context.WithValue(ctx, readyKey{}, ready)
+}
+
+// dependsOn returns the Tasks t depends on, if it is a DependentTask.
+func dependsOn(t Task) []Task {
+	if dt, ok := t.(DependentTask); ok {
+		return dt.DependsOn()
+	}
+
+	return nil
+}
+
+// checkDependencies checks the dependencies of each Task are also in tasks,
+// and don't depend on the Task in turn.
+func checkDependencies(tasks []Task) error {
+	for _, t := range tasks {
+		for _, dep := range dependsOn(t) {
+			if !slices.Contains(tasks, dep) {
+				return fmt.Errorf("%s depends on %s, which isn't added", t.TaskName(), dep.TaskName())
+			}
+		}
+	}
+
+	// visiting are the Tasks on the path being checked, and visited those
+	// already checked.
+	visiting := map[Task]bool{}
+	visited := map[Task]bool{}
+	path := []string{}
+
+	var visit func(t Task) error
+	visit = func(t Task) error {
+		if visited[t] {
+			return nil
+		}
+
+		path = append(path, t.TaskName())
+		defer func() { path = path[:len(path)-1] }()
+
+		if visiting[t] {
+			return fmt.Errorf("dependency cycle %s", strings.Join(path, " -> "))
+		}
+
+		visiting[t] = true
+
+		for _, dep := range dependsOn(t) {
+			err := visit(dep)
+			if err != nil {
+				return err
+			}
+		}
+
+		visiting[t] = false
+		visited[t] = true
+
+		return nil
+	}
+
+	for _, t := range tasks {
+		err := visit(t)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,210 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// orderTask is a Task recording when it starts, is ready and stops, which is
+// ready after delay.
+type orderTask struct {
+	name    string
+	delay   time.Duration
+	timeout time.Duration
+	deps    []Task
+	events  *events
+}
+
+func (t *orderTask) TaskName() string {
+	return t.name
+}
+
+func (t *orderTask) ReadyTimeout() time.Duration {
+	return t.timeout
+}
+
+func (t *orderTask) DependsOn() []Task {
+	return t.deps
+}
+
+func (t *orderTask) RunTask(ctx context.Context) error {
+	t.events.add("start " + t.name)
+
+	select {
+	case <-time.After(t.delay):
+		t.events.add("ready " + t.name)
+		Ready(ctx)
+	case <-ctx.Done():
+	}
+
+	<-ctx.Done()
+
+	// Tasks depending on this one have stopped by now, whose events are
+	// recorded before this one.
+	time.Sleep(10 * time.Millisecond)
+	t.events.add("stop " + t.name)
+
+	return ctx.Err()
+}
+
+// events records the order of events of Tasks.
+type events struct {
+	mu   sync.Mutex
+	list []string
+}
+
+func (e *events) add(event string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.list = append(e.list, event)
+}
+
+func (e *events) get() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return slices.Clone(e.list)
+}
+
+func TestRunnerDependencyOrder(t *testing.T) {
+	ev := &events{}
+
+	db := &orderTask{name: "db", delay: 50 * time.Millisecond, events: ev}
+	cache := &orderTask{name: "cache", delay: 20 * time.Millisecond, deps: []Task{db}, events: ev}
+	api := &orderTask{name: "api", deps: []Task{db, cache}, events: ev}
+
+	r := &Runner{}
+
+	// Tasks are started in dependency order regardless of the order they
+	// are added in.
+	r.Add(api)
+	r.Add(cache)
+	r.Add(db)
+
+	ready := make(chan struct{})
+	r.TasksReady = func() {
+		ev.add("tasks ready")
+		close(ready)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- r.Run(ctx)
+	}()
+
+	select {
+	case <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("tasks not ready")
+	}
+
+	cancel()
+	<-stopped
+
+	want := []string{
+		"start db", "ready db",
+		"start cache", "ready cache",
+		"start api", "ready api",
+		"tasks ready",
+		"stop api", "stop cache", "stop db",
+	}
+
+	if got := ev.get(); !slices.Equal(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+}
+
+func TestRunnerReadyTimeout(t *testing.T) {
+	ev := &events{}
+
+	slow := &orderTask{name: "slow", delay: time.Minute, timeout: 20 * time.Millisecond, events: ev}
+
+	r := &Runner{}
+	r.Add(slow)
+
+	err := r.Run(context.Background())
+
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Name != "slow" || !strings.Contains(err.Error(), "not ready within 20ms") {
+		t.Fatalf("err = %v, want slow not ready in time", err)
+	}
+}
+
+func TestRunnerReadyWithinTimeout(t *testing.T) {
+	ev := &events{}
+
+	r := &Runner{}
+	r.Add(&orderTask{name: "a", delay: 10 * time.Millisecond, timeout: time.Second, events: ev})
+
+	stopped := startRunner(t, r)
+
+	// the timeout no longer applies once ready.
+	time.Sleep(1100 * time.Millisecond)
+	assertRunning(t, stopped)
+}
+
+func TestRunnerDependencyErrors(t *testing.T) {
+	a := &orderTask{name: "a"}
+	b := &orderTask{name: "b", deps: []Task{a}}
+	a.deps = []Task{b}
+
+	r := &Runner{}
+	r.Add(a)
+	r.Add(b)
+
+	err := r.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "dependency cycle a -> b -> a") {
+		t.Fatalf("err = %v, want a dependency cycle", err)
+	}
+
+	r = &Runner{}
+	r.Add(&orderTask{name: "c", deps: []Task{&orderTask{name: "missing"}}})
+
+	err = r.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "c depends on missing, which isn't added") {
+		t.Fatalf("err = %v, want a missing dependency", err)
+	}
+}
+
+func TestRunnerSupervisorDependency(t *testing.T) {
+	ev := &events{}
+
+	pool := &orderTask{name: "pool", delay: 20 * time.Millisecond, events: ev}
+
+	child := &orderTask{name: "child", events: ev}
+
+	s := &Supervisor{Name: "test", Dependencies: []Task{pool}}
+	s.Add(child)
+
+	r := &Runner{}
+	r.Add(s)
+	r.Add(pool)
+
+	ctx, cancel := context.WithCancel(quietContext())
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- r.Run(ctx)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !slices.Contains(ev.get(), "ready child") && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	<-stopped
+
+	want := []string{"start pool", "ready pool", "start child", "ready child", "stop child", "stop pool"}
+	if got := ev.get(); !slices.Equal(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,163 @@
+package tasks
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/svalevka/go/pkg/config"
+)
+
+//go:generate go run github.com/svalevka/go/pkg/config/docgen
+
+// Restart policies of RestartPolicy.
+const (
+	RestartNever     = "never"
+	RestartOnFailure = "on-failure"
+	RestartAlways    = "always"
+)
+
+// RestartPolicy configures whether a task is restarted when it stops, waiting
+// an exponentially increasing delay between restarts.
+type RestartPolicy struct {
+	// Restart is when the task is restarted, either never, on-failure when
+	// it returns an error, or always, including when it returns without one.
+	Restart string `yaml:"restart" default:"on-failure" validate:"oneof=never on-failure always"`
+
+	// Backoff is the delay before the first restart, doubled by each
+	// consecutive restart, or zero to restart immediately.
+	Backoff time.Duration `yaml:"backoff" default:"1s" validate:"min=0s"`
+
+	// MaxBackoff is the longest delay between restarts, or unlimited if
+	// zero.
+	MaxBackoff time.Duration `yaml:"max_backoff" default:"1m" validate:"min=0s"`
+
+	// Jitter is the fraction of each delay randomly added or subtracted, so
+	// tasks failing together don't restart together.
+	Jitter float64 `yaml:"jitter" default:"0.2" validate:"min=0,max=1"`
+
+	// MaxRestarts is the number of restarts allowed within Window, after
+	// which the task fails, or unlimited if zero.
+	MaxRestarts int `yaml:"max_restarts" default:"10" validate:"min=0"`
+
+	// Window is the period MaxRestarts are counted over, or the lifetime of
+	// the task if zero.
+	Window time.Duration `yaml:"window" default:"10m" validate:"min=0s"`
+
+	// ResetAfter is how long a task must run before it is considered stable,
+	// resetting its delay and restart count, or never if zero.
+	ResetAfter time.Duration `yaml:"reset_after" default:"5m" validate:"min=0s"`
+}
+
+// defaultRestartPolicy returns the RestartPolicy given by the default tags of
+// its fields, used for Tasks that aren't a RestartTask.
+func defaultRestartPolicy() *RestartPolicy {
+	return &RestartPolicy{
+		Restart:     RestartOnFailure,
+		Backoff:     time.Second,
+		MaxBackoff:  time.Minute,
+		Jitter:      0.2,
+		MaxRestarts: 10,
+		Window:      10 * time.Minute,
+		ResetAfter:  5 * time.Minute,
+	}
+}
+
+// Validate checks Backoff is not longer than MaxBackoff.
+func (p *RestartPolicy) Validate() error {
+	if p.MaxBackoff != 0 && p.Backoff > p.MaxBackoff {
+		return &config.ValidationError{Field: "backoff", Message: fmt.Sprintf("must not be longer than max_backoff %s", p.MaxBackoff)}
+	}
+
+	return nil
+}
+
+// restarter tracks the restarts of a Task by its RestartPolicy.
+type restarter struct {
+	// policy is the RestartPolicy of the Task, or nil if it is never
+	// restarted.
+	policy *RestartPolicy
+
+	// attempt is the number of consecutive restarts since the Task was last
+	// stable, and restarts are the times of those within the window.
+	attempt  int
+	restarts []time.Time
+}
+
+// next is given the error returned by a Task and how long it ran for, and
+// returns whether it is restarted and the delay before restarting it. An error
+// is returned instead if the Task has been restarted MaxRestarts times within
+// Window.
+func (r *restarter) next(err error, ran time.Duration) (time.Duration, bool, error) {
+	if !restarts(r.policy, err) {
+		return 0, false, nil
+	}
+
+	now := time.Now()
+
+	if r.policy.ResetAfter > 0 && ran >= r.policy.ResetAfter {
+		r.attempt, r.restarts = 0, nil
+	}
+
+	if r.policy.Window > 0 {
+		recent := r.restarts[:0]
+		for _, t := range r.restarts {
+			if now.Sub(t) < r.policy.Window {
+				recent = append(recent, t)
+			}
+		}
+
+		r.restarts = recent
+	}
+
+	if r.policy.MaxRestarts > 0 && len(r.restarts) >= r.policy.MaxRestarts {
+		if r.policy.Window > 0 {
+			return 0, false, fmt.Errorf("restarted %d times within %s", len(r.restarts), r.policy.Window)
+		}
+
+		return 0, false, fmt.Errorf("restarted %d times", len(r.restarts))
+	}
+
+	delay := backoff(r.policy, r.attempt)
+
+	r.attempt++
+	r.restarts = append(r.restarts, now)
+
+	return delay, true, nil
+}
+
+// restarts returns whether a Task returning err is restarted by policy.
+func restarts(policy *RestartPolicy, err error) bool {
+	if policy == nil {
+		return false
+	}
+
+	switch policy.Restart {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return err != nil
+	default:
+		return false
+	}
+}
+
+// backoff returns the jittered delay of policy before the restart following
+// attempt consecutive restarts.
+func backoff(policy *RestartPolicy, attempt int) time.Duration {
+	delay := policy.Backoff
+	for i := 0; i < attempt && delay <= math.MaxInt64/2; i++ {
+		if policy.MaxBackoff > 0 && delay >= policy.MaxBackoff {
+			break
+		}
+
+		delay *= 2
+	}
+
+	if policy.MaxBackoff > 0 {
+		delay = min(delay, policy.MaxBackoff)
+	}
+
+	return time.Duration(float64(delay) * (1 + policy.Jitter*(2*rand.Float64()-1)))
+}
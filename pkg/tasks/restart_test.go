@@ -0,0 +1,185 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/svalevka/go/pkg/config"
+)
+
+// funcTask is a Task running a function.
+type funcTask struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+func (f *funcTask) TaskName() string {
+	return f.name
+}
+
+func (f *funcTask) RunTask(ctx context.Context) error {
+	return f.run(ctx)
+}
+
+func TestRestarterBackoff(t *testing.T) {
+	r := &restarter{policy: &RestartPolicy{
+		Restart:    RestartOnFailure,
+		Backoff:    time.Second,
+		MaxBackoff: 5 * time.Second,
+	}}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		delay, restart, err := r.next(errors.New("failed"), 0)
+		if err != nil || !restart {
+			t.Fatalf("restart %d: restart = %t, err = %v", i, restart, err)
+		}
+
+		if delay != w {
+			t.Errorf("restart %d: delay = %s, want %s", i, delay, w)
+		}
+	}
+}
+
+func TestRestarterZeroBackoff(t *testing.T) {
+	r := &restarter{policy: &RestartPolicy{Restart: RestartAlways}}
+
+	for i := 0; i < 100; i++ {
+		delay, restart, err := r.next(nil, 0)
+		if err != nil || !restart || delay != 0 {
+			t.Fatalf("restart %d: delay = %s, restart = %t, err = %v, want immediate", i, delay, restart, err)
+		}
+	}
+}
+
+func TestRestarterJitter(t *testing.T) {
+	r := &restarter{policy: &RestartPolicy{
+		Restart: RestartAlways,
+		Backoff: time.Second,
+		Jitter:  0.5,
+	}}
+
+	delay, _, _ := r.next(nil, 0)
+	if delay < 500*time.Millisecond || delay > 1500*time.Millisecond {
+		t.Errorf("delay = %s, want within 50%% of 1s", delay)
+	}
+}
+
+func TestRestarterPolicy(t *testing.T) {
+	failed := errors.New("failed")
+
+	tests := []struct {
+		restart string
+		err     error
+		want    bool
+	}{
+		{RestartNever, failed, false},
+		{RestartOnFailure, failed, true},
+		{RestartOnFailure, nil, false},
+		{RestartAlways, nil, true},
+		{"", failed, false},
+	}
+
+	for _, tt := range tests {
+		r := &restarter{policy: &RestartPolicy{Restart: tt.restart}}
+
+		_, restart, err := r.next(tt.err, 0)
+		if err != nil || restart != tt.want {
+			t.Errorf("%q with %v: restart = %t, err = %v, want %t", tt.restart, tt.err, restart, err, tt.want)
+		}
+	}
+
+	_, restart, _ := (&restarter{}).next(failed, 0)
+	if restart {
+		t.Error("want no restart without a policy")
+	}
+}
+
+func TestRestarterLimit(t *testing.T) {
+	r := &restarter{policy: &RestartPolicy{
+		Restart:     RestartOnFailure,
+		MaxRestarts: 3,
+		Window:      time.Minute,
+		ResetAfter:  time.Hour,
+	}}
+
+	for i := 0; i < 3; i++ {
+		_, restart, err := r.next(errors.New("failed"), 0)
+		if err != nil || !restart {
+			t.Fatalf("restart %d: restart = %t, err = %v", i, restart, err)
+		}
+	}
+
+	_, restart, err := r.next(errors.New("failed"), 0)
+	if err == nil || restart {
+		t.Fatalf("restart = %t, err = %v, want the limit reached", restart, err)
+	}
+
+	// a Task running for ResetAfter is stable, so its restarts are reset.
+	_, restart, err = r.next(errors.New("failed"), time.Hour)
+	if err != nil || !restart {
+		t.Fatalf("restart = %t, err = %v, want a restart once stable", restart, err)
+	}
+}
+
+func TestRestarterWindow(t *testing.T) {
+	r := &restarter{policy: &RestartPolicy{
+		Restart:     RestartOnFailure,
+		MaxRestarts: 1,
+		Window:      10 * time.Millisecond,
+	}}
+
+	_, _, err := r.next(errors.New("failed"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	_, restart, err := r.next(errors.New("failed"), 0)
+	if err != nil || !restart {
+		t.Fatalf("restart = %t, err = %v, want the earlier restart outside the window", restart, err)
+	}
+}
+
+func TestRestarterDefaults(t *testing.T) {
+	r := newRestarter(&funcTask{name: "plain"})
+
+	want := &RestartPolicy{}
+
+	err := config.SetDefaults(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *r.policy != *want {
+		t.Errorf("policy = %+v, want the defaults %+v", r.policy, want)
+	}
+}
+
+func TestRestartPolicyValidation(t *testing.T) {
+	for _, tt := range []struct {
+		policy RestartPolicy
+		want   string
+	}{
+		{RestartPolicy{}, ""},
+		{RestartPolicy{Restart: "sometimes"}, "restart: must be one of never, on-failure, always"},
+		{RestartPolicy{Backoff: 2 * time.Minute}, "backoff: must not be longer than max_backoff 1m0s"},
+		{RestartPolicy{Jitter: 2}, "jitter:"},
+	} {
+		p := tt.policy
+
+		err := config.SetDefaults(&p)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = config.Validate(&p)
+		if tt.want == "" && err != nil || tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("Validate(%+v) = %v, want %q", tt.policy, err, tt.want)
+		}
+	}
+}
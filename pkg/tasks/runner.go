@@ -2,7 +2,14 @@ package tasks
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
 	"sync"
+	"time"
+
+	"github.com/svalevka/go/pkg/log"
 )
 
 // TaskStatus is given to Runner callbacks to indicate the status of the tasks
@@ -10,6 +17,9 @@ import (
 type TasksStatus struct {
 	Name    string
 	Running bool
+
+	// Restarts is the number of times the Task has been restarted.
+	Restarts int
 }
 
 // Task is implemented by concurrent tasks a programme is expected to execute.
@@ -23,7 +33,36 @@ type Task interface {
 	RunTask(context.Context) error
 }
 
-// Runner manages the execution of concurrent Tasks.
+// RestartTask is implemented by Tasks that are restarted by the Runner when
+// they stop according to their RestartPolicy, rather than stopping the Runner
+// when they fail. Tasks are only failed once their restart limit is reached.
+type RestartTask interface {
+	Task
+
+	// RestartPolicy returns the RestartPolicy of the Task.
+	RestartPolicy() *RestartPolicy
+}
+
+// TaskError is the error of a Task that failed, as returned by Runner.Run.
+type TaskError struct {
+	// Name is the name of the Task.
+	Name string
+
+	// Err is the error returned by the Task.
+	Err error
+}
+
+func (e *TaskError) Error() string {
+	return e.Name + ": " + e.Err.Error()
+}
+
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
+// Runner manages the execution of concurrent Tasks as a group, where the
+// first Task to fail stops every other Task. Tasks depending on others, see
+// DependentTask, are started once those are ready, and stopped before them.
 type Runner struct {
 	// TaskStarting is called before each Task is started by the Runner.
 	TaskStarting func(*TasksStatus)
@@ -34,44 +73,548 @@ type Runner struct {
 	// TaskFailed is called after a Task returns an unexpected error.
 	TaskFailed func(*TasksStatus, error)
 
-	tasks []Task
+	// TaskRestarting is called after a RestartTask stops, with the error it
+	// returned if any, and the delay before it is started again.
+	TaskRestarting func(*TasksStatus, error, time.Duration)
+
+	// TasksReady is called once every Task started by Run is ready, see
+	// ReadyTask.
+	TasksReady func()
+
+	mu    sync.Mutex
+	tasks []*runningTask
+
+	// ctx and cancel are set while the Runner is running, so Tasks added
+	// during Run are started immediately, and a failing Task can stop the
+	// others.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// running is the number of Tasks running, and idle is closed once it
+	// drops to zero, so Run returns once every Task has stopped, including
+	// those started while it was returning.
+	running int
+	idle    chan struct{}
+
+	// errs are the errors of the Tasks that failed during Run.
+	errs []error
 }
 
-// Add attaches a Task to a Runner, to be run when Run is called.
+// runningTask tracks a Task added to a Runner, and how to stop it.
+type runningTask struct {
+	task   Task
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// ready is closed once the Task is first ready.
+	ready chan struct{}
+
+	// starting is given the outcome of a Task added by Start, once it is
+	// ready or fails.
+	starting chan error
+}
+
+// Add attaches a Task to a Runner, to be run when Run is called. If the Runner
+// is already running, the Task is started immediately, once the Tasks it
+// depends on are ready.
 func (r *Runner) Add(t Task) {
-	r.tasks = append(r.tasks, t)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rt := &runningTask{task: t, ready: make(chan struct{})}
+	r.tasks = append(r.tasks, rt)
+
+	if r.ctx != nil {
+		r.start(rt)
+	}
+}
+
+// Start is equivalent to Add, but if the Runner is already running, waits for
+// the Task to be ready and returns nil, see ReadyTask. A Task failing or
+// stopping before it is ready is removed from the Runner, and its error
+// returned, rather than stopping every other Task, so Tasks can be started in
+// response to events such as configuration reloads.
+func (r *Runner) Start(t Task) error {
+	r.mu.Lock()
+
+	rt := &runningTask{task: t, ready: make(chan struct{})}
+	r.tasks = append(r.tasks, rt)
+
+	if r.ctx == nil {
+		r.mu.Unlock()
+		return nil
+	}
+
+	starting := make(chan error, 1)
+	rt.starting = starting
+
+	r.start(rt)
+
+	r.mu.Unlock()
+
+	return <-starting
+}
+
+// Replace swaps a Task of the Runner for another. If the Runner is running, the
+// old Task is stopped before the new one is started, so it can take over its
+// resources such as a listening address, and the new Task is waited for as by
+// Start. If it fails, the old Task is started again and the error returned.
+// The Runner keeps running in the meantime, even if the old Task is its only
+// Task.
+func (r *Runner) Replace(old, new Task) error {
+	r.mu.Lock()
+
+	if r.ctx == nil {
+		idx := slices.IndexFunc(r.tasks, func(rt *runningTask) bool { return rt.task == old })
+		if idx < 0 {
+			r.tasks = append(r.tasks, &runningTask{task: new, ready: make(chan struct{})})
+		} else {
+			r.tasks[idx] = &runningTask{task: new, ready: make(chan struct{})}
+		}
+
+		r.mu.Unlock()
+
+		return nil
+	}
+
+	r.running++
+	r.mu.Unlock()
+
+	defer r.release()
+
+	r.Remove(old)
+
+	err := r.Start(new)
+	if err != nil {
+		return errors.Join(err, r.Start(old))
+	}
+
+	return nil
+}
+
+// Remove detaches a Task from a Runner, canceling its context if it is running
+// and waiting for it to stop.
+func (r *Runner) Remove(t Task) {
+	r.mu.Lock()
+
+	var found *runningTask
+	for i, rt := range r.tasks {
+		if rt.task == t {
+			found = rt
+			r.tasks = append(r.tasks[:i], r.tasks[i+1:]...)
+			break
+		}
+	}
+
+	r.mu.Unlock()
+
+	if found != nil && found.cancel != nil {
+		found.cancel()
+		<-found.done
+	}
 }
 
 // Run starts all the Tasks that have been Added to the Runner, until the
-// context is canceled or a Task returns an unexpected error.
+// context is canceled or a Task returns an unexpected error, which cancels
+// every other Task. Run returns once every Task has stopped.
+//
+// If any Task failed, the errors of every failed Task are returned joined, each
+// a *TaskError. Otherwise the error of the context is returned if it was
+// canceled, or nil if every Task stopped by itself. An error is returned
+// without starting any Task if their dependencies are missing or circular.
 func (r *Runner) Run(ctx context.Context) error {
-	wg := new(sync.WaitGroup)
+	r.mu.Lock()
+
+	err := checkDependencies(r.taskList())
+	if err != nil {
+		r.mu.Unlock()
+		return err
+	}
+
+	r.ctx, r.cancel = context.WithCancel(ctx)
+	r.running = 0
+	r.idle = make(chan struct{})
+	r.errs = nil
+
+	ready := []chan struct{}{}
+
+	for _, rt := range r.tasks {
+		rt.ready = make(chan struct{})
+		ready = append(ready, rt.ready)
+	}
+
+	for _, rt := range r.tasks {
+		r.start(rt)
+	}
+
+	if r.TasksReady != nil {
+		go r.waitReady(r.ctx, ready)
+	}
+
+	r.mu.Unlock()
+
+	r.waitIdle()
+
+	r.cancel()
+	r.ctx, r.cancel = nil, nil
+	errs := r.errs
+	r.mu.Unlock()
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	return ctx.Err()
+}
+
+// waitIdle waits for every Task to stop, returning with the Runner locked.
+func (r *Runner) waitIdle() {
+	r.mu.Lock()
+
+	for r.running > 0 {
+		idle := r.idle
+		r.mu.Unlock()
+
+		<-idle
+
+		r.mu.Lock()
+	}
+}
+
+// release marks a Task started by start, or held by Replace, as stopped.
+func (r *Runner) release() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.running--
+	if r.running == 0 {
+		close(r.idle)
+		r.idle = make(chan struct{})
+	}
+}
+
+// taskList returns the Tasks added to the Runner, the Runner must be locked.
+func (r *Runner) taskList() []Task {
+	tasks := make([]Task, len(r.tasks))
+	for i, rt := range r.tasks {
+		tasks[i] = rt.task
+	}
+
+	return tasks
+}
+
+// waitReady calls TasksReady once every Task is ready, unless the Runner is
+// stopped first.
+func (r *Runner) waitReady(ctx context.Context, ready []chan struct{}) {
+	for _, ch := range ready {
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	r.TasksReady()
+}
+
+// start runs a Task in a new goroutine with its own cancelable context, which
+// carries the name of the Task as the task attribute of log.FromContext, the
+// Runner must be locked. The context is canceled once the Runner is stopped
+// and the Tasks depending on the Task have stopped.
+func (r *Runner) start(rt *runningTask) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(log.With(r.ctx, slog.String("task", rt.task.TaskName()))))
 
-	for _, task := range r.tasks {
-		wg.Add(1)
+	rt.cancel = cancel
+	rt.done = make(chan struct{})
 
-		go func(ctx context.Context, wg *sync.WaitGroup, task Task) {
-			defer wg.Done()
+	r.running++
 
-			if r.TaskStarting != nil {
-				r.TaskStarting(&TasksStatus{Name: task.TaskName(), Running: true})
+	go func(ctx context.Context, rt *runningTask) {
+		defer r.release()
+		defer close(rt.done)
+		defer rt.cancel()
+
+		r.run(ctx, rt)
+	}(ctx, rt)
+
+	go r.stopAfterDependents(r.ctx, rt)
+}
+
+// stopAfterDependents cancels a Task once the Runner is stopped, after the
+// Tasks depending on it have stopped.
+func (r *Runner) stopAfterDependents(ctx context.Context, rt *runningTask) {
+	select {
+	case <-ctx.Done():
+	case <-rt.done:
+		return
+	}
+
+	r.mu.Lock()
+
+	dependents := []*runningTask{}
+	for _, other := range r.tasks {
+		if other.done != nil && slices.Contains(dependsOn(other.task), rt.task) {
+			dependents = append(dependents, other)
+		}
+	}
+
+	r.mu.Unlock()
+
+	for _, other := range dependents {
+		<-other.done
+	}
+
+	rt.cancel()
+}
+
+// setReady marks a Task as ready.
+func (r *Runner) setReady(rt *runningTask) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	select {
+	case <-rt.ready:
+	default:
+		close(rt.ready)
+	}
+
+	if rt.starting != nil {
+		rt.starting <- nil
+		rt.starting = nil
+	}
+}
+
+// startFailed reports err to the caller of Start if the Task is still being
+// started by it, removing the Task from the Runner, and returns whether it was.
+func (r *Runner) startFailed(rt *runningTask, err error) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if rt.starting == nil {
+		return false
+	}
+
+	if err == nil {
+		err = errors.New("stopped before it was ready")
+	}
+
+	rt.starting <- &TaskError{Name: rt.task.TaskName(), Err: err}
+	rt.starting = nil
+
+	r.tasks = slices.DeleteFunc(r.tasks, func(other *runningTask) bool { return other == rt })
+
+	return true
+}
+
+// waitDependencies waits for the Tasks a Task depends on to be ready.
+func (r *Runner) waitDependencies(ctx context.Context, rt *runningTask) error {
+	r.mu.Lock()
+
+	// Tasks added while running are checked when they are started.
+	err := checkDependencies(r.taskList())
+
+	deps := []*runningTask{}
+	for _, dep := range dependsOn(rt.task) {
+		idx := slices.IndexFunc(r.tasks, func(other *runningTask) bool { return other.task == dep })
+		if idx < 0 {
+			err = fmt.Errorf("depends on %s, which isn't added", dep.TaskName())
+			break
+		}
+
+		deps = append(deps, r.tasks[idx])
+	}
+
+	r.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	for _, dep := range deps {
+		select {
+		case <-dep.ready:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// runReady runs a Task once, failing it if it is a ReadyTask that isn't ready
+// within its ReadyTimeout.
+func (r *Runner) runReady(ctx context.Context, rt *runningTask) error {
+	readyTask, ok := rt.task.(ReadyTask)
+	if !ok {
+		r.setReady(rt)
+		return rt.task.RunTask(withReady(ctx, nil))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ready := make(chan struct{})
+	var once sync.Once
+
+	ctx = withReady(ctx, func() {
+		once.Do(func() {
+			close(ready)
+			r.setReady(rt)
+		})
+	})
+
+	timeout := readyTask.ReadyTimeout()
+	if timeout <= 0 {
+		return rt.task.RunTask(ctx)
+	}
+
+	// notReady is set if the Task is canceled for not being ready in time.
+	notReady := make(chan error, 1)
+
+	go func() {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+			notReady <- fmt.Errorf("not ready within %s", timeout)
+			cancel()
+		case <-ready:
+		case <-ctx.Done():
+		}
+	}()
+
+	err := rt.task.RunTask(ctx)
+
+	select {
+	case readyErr := <-notReady:
+		if err == nil || errors.Is(err, context.Canceled) {
+			err = readyErr
+		}
+	default:
+	}
+
+	return err
+}
+
+// run runs a Task until it stops, restarting it if it is a RestartTask.
+func (r *Runner) run(ctx context.Context, rt *runningTask) {
+	task := rt.task
+
+	restarter := &restarter{}
+	if rt, ok := task.(RestartTask); ok {
+		restarter.policy = rt.RestartPolicy()
+	}
+
+	status := TasksStatus{Name: task.TaskName()}
+
+	err := r.waitDependencies(ctx, rt)
+	if err != nil {
+		if r.startFailed(rt, err) {
+			return
+		}
+
+		if ctx.Err() == nil {
+			if r.TaskFailed != nil {
+				r.TaskFailed(status.copy(), err)
 			}
 
-			err := task.RunTask(ctx)
-			if err != nil {
-				if r.TaskFailed != nil {
-					r.TaskFailed(&TasksStatus{Name: task.TaskName(), Running: false}, err)
+			r.fail(&TaskError{Name: task.TaskName(), Err: err})
+		}
+
+		return
+	}
+
+	for {
+		status.Running = true
+
+		if r.TaskStarting != nil {
+			r.TaskStarting(status.copy())
+		}
+
+		started := time.Now()
+		err := r.runReady(ctx, rt)
+
+		status.Running = false
+
+		// a Task returning because it was asked to stop, either by the
+		// Runner context or by Remove, has stopped gracefully.
+		if errors.Is(err, context.Canceled) && ctx.Err() != nil {
+			err = nil
+		}
+
+		// Tasks added by Start aren't restarted until they are first
+		// ready, as their failure is returned instead.
+		if r.startFailed(rt, err) {
+			return
+		}
+
+		if ctx.Err() == nil {
+			delay, restart, limitErr := restarter.next(err, time.Since(started))
+
+			switch {
+			case limitErr != nil && err != nil:
+				err = fmt.Errorf("%w: %w", limitErr, err)
+			case limitErr != nil:
+				err = limitErr
+			case restart:
+				if r.TaskRestarting != nil {
+					r.TaskRestarting(status.copy(), err, delay)
 				}
-			} else {
-				if r.TaskStopped != nil {
-					r.TaskStopped(&TasksStatus{Name: task.TaskName(), Running: false})
+
+				if r.wait(ctx, delay) {
+					status.Restarts++
+					continue
 				}
+
+				err = nil
+			}
+		}
+
+		if err != nil {
+			if r.TaskFailed != nil {
+				r.TaskFailed(status.copy(), err)
+			}
+
+			r.fail(&TaskError{Name: task.TaskName(), Err: err})
+		} else {
+			if r.TaskStopped != nil {
+				r.TaskStopped(status.copy())
 			}
-		}(ctx, wg, task)
+		}
+
+		return
 	}
+}
+
+// copy returns a copy of the TasksStatus, given to callbacks so they may keep
+// it.
+func (s TasksStatus) copy() *TasksStatus {
+	return &s
+}
 
-	// TODO(jc): shutdown other tasks when one fails.
+// wait waits for the delay before restarting a Task, returning false if the
+// Task is stopped in the meantime.
+func (r *Runner) wait(ctx context.Context, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
 
-	wg.Wait()
-	return nil
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// fail records the error of a failed Task and stops every other Task.
+func (r *Runner) fail(err *TaskError) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.errs = append(r.errs, err)
+
+	if r.cancel != nil {
+		r.cancel()
+	}
 }
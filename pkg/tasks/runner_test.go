@@ -0,0 +1,536 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/svalevka/go/pkg/log"
+)
+
+// readyTask is a ReadyTask which is ready once started, unless it fails with
+// err first.
+type readyTask struct {
+	name string
+	err  error
+}
+
+func (t *readyTask) TaskName() string {
+	return t.name
+}
+
+func (t *readyTask) ReadyTimeout() time.Duration {
+	return 0
+}
+
+func (t *readyTask) RunTask(ctx context.Context) error {
+	if t.err != nil {
+		return t.err
+	}
+
+	Ready(ctx)
+	<-ctx.Done()
+
+	return ctx.Err()
+}
+
+// blockingTask returns a Task running until it is stopped.
+func blockingTask(name string) *funcTask {
+	return &funcTask{name: name, run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+}
+
+// startRunner runs r until the test ends, waiting for its Tasks to be ready,
+// and returns the channel given the error of Run.
+func startRunner(t *testing.T, r *Runner) chan error {
+	t.Helper()
+
+	ready := make(chan struct{})
+	r.TasksReady = func() { close(ready) }
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- r.Run(ctx)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			t.Error("runner didn't stop")
+		}
+	})
+
+	select {
+	case <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("tasks not ready")
+	}
+
+	return stopped
+}
+
+// assertRunning fails the test if the Runner stops within a moment.
+func assertRunning(t *testing.T, stopped chan error) {
+	t.Helper()
+
+	select {
+	case err := <-stopped:
+		t.Fatalf("runner stopped with %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestRunnerStart(t *testing.T) {
+	r := &Runner{}
+	r.Add(blockingTask("a"))
+
+	stopped := startRunner(t, r)
+
+	err := r.Start(&readyTask{name: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failed := errors.New("address in use")
+
+	err = r.Start(&readyTask{name: "c", err: failed})
+
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Name != "c" || !errors.Is(err, failed) {
+		t.Fatalf("err = %v, want the TaskError of c", err)
+	}
+
+	assertRunning(t, stopped)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if names := r.taskList(); len(names) != 2 {
+		t.Errorf("tasks = %v, want the failed task removed", names)
+	}
+}
+
+func TestRunnerTaskContext(t *testing.T) {
+	attrs := make(chan []slog.Attr, 1)
+
+	r := &Runner{}
+	r.Add(&funcTask{name: "a", run: func(ctx context.Context) error {
+		attrs <- log.Attrs(ctx)
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+
+	startRunner(t, r)
+
+	select {
+	case got := <-attrs:
+		if len(got) != 1 || got[0].String() != "task=a" {
+			t.Errorf("attrs = %v, want the task name", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("task not started")
+	}
+}
+
+func TestRunnerStartNotRunning(t *testing.T) {
+	r := &Runner{}
+
+	// Tasks started before Run fail the Runner as any other.
+	err := r.Start(&readyTask{name: "a", err: errors.New("failed")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Run(context.Background())
+
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Name != "a" {
+		t.Fatalf("err = %v, want the TaskError of a", err)
+	}
+}
+
+func TestRunnerReplace(t *testing.T) {
+	r := &Runner{}
+
+	old := &readyTask{name: "old"}
+	r.Add(old)
+
+	stopped := startRunner(t, r)
+
+	// the Runner keeps running while its only Task is replaced.
+	err := r.Replace(old, &readyTask{name: "new"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertRunning(t, stopped)
+
+	r.mu.Lock()
+	tasks := r.taskList()
+	r.mu.Unlock()
+
+	if len(tasks) != 1 || tasks[0].TaskName() != "new" {
+		t.Fatalf("tasks = %v, want new", tasks)
+	}
+
+	err = r.Replace(tasks[0], &readyTask{name: "failing", err: errors.New("failed")})
+	if err == nil {
+		t.Fatal("want the error of the failing task")
+	}
+
+	assertRunning(t, stopped)
+
+	r.mu.Lock()
+	tasks = r.taskList()
+	r.mu.Unlock()
+
+	if len(tasks) != 1 || tasks[0].TaskName() != "new" {
+		t.Fatalf("tasks = %v, want new restored", tasks)
+	}
+}
+
+func TestRunnerReplaceNotRunning(t *testing.T) {
+	r := &Runner{}
+
+	a, b := blockingTask("a"), blockingTask("b")
+	r.Add(a)
+
+	err := r.Replace(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tasks := r.taskList(); len(tasks) != 1 || tasks[0] != b {
+		t.Fatalf("tasks = %v, want b", tasks)
+	}
+}
+
+func TestRunnerFailFast(t *testing.T) {
+	failed := errors.New("failed")
+
+	stoppedB := make(chan struct{})
+
+	r := &Runner{}
+	r.Add(&funcTask{name: "a", run: func(ctx context.Context) error {
+		return failed
+	}})
+	r.Add(&funcTask{name: "b", run: func(ctx context.Context) error {
+		<-ctx.Done()
+		close(stoppedB)
+		return ctx.Err()
+	}})
+
+	err := r.Run(context.Background())
+
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Name != "a" || !errors.Is(err, failed) {
+		t.Fatalf("err = %v, want the TaskError of a", err)
+	}
+
+	select {
+	case <-stoppedB:
+	default:
+		t.Fatal("want b stopped when a fails")
+	}
+}
+
+func TestRunnerJoinsErrors(t *testing.T) {
+	r := &Runner{}
+
+	// a Task failing while being stopped is reported too.
+	for _, name := range []string{"a", "b"} {
+		name := name
+		r.Add(&funcTask{name: name, run: func(ctx context.Context) error {
+			return errors.New(name + " failed")
+		}})
+	}
+
+	err := r.Run(context.Background())
+
+	names := map[string]bool{}
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var taskErr *TaskError
+		if errors.As(err, &taskErr) {
+			names[taskErr.Name] = true
+		}
+	}
+
+	if !names["a"] || !names["b"] {
+		t.Fatalf("err = %v, want the TaskErrors of a and b", err)
+	}
+}
+
+func TestRunnerStops(t *testing.T) {
+	r := &Runner{}
+	r.Add(blockingTask("a"))
+	r.Add(&funcTask{name: "b", run: func(ctx context.Context) error {
+		return nil
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- r.Run(ctx)
+	}()
+
+	// a Task stopping by itself doesn't stop the others.
+	assertRunning(t, stopped)
+
+	cancel()
+
+	err := <-stopped
+	if err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestRunnerStopsByItself(t *testing.T) {
+	r := &Runner{}
+	r.Add(&funcTask{name: "a", run: func(ctx context.Context) error {
+		return nil
+	}})
+
+	err := r.Run(context.Background())
+	if err != nil {
+		t.Fatalf("err = %v, want nil once every task stopped", err)
+	}
+}
+
+func TestRunnerCallbacks(t *testing.T) {
+	failed := errors.New("failed")
+
+	var mu sync.Mutex
+	events := []string{}
+
+	record := func(event string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		events = append(events, event)
+	}
+
+	r := &Runner{
+		TaskStarting: func(s *TasksStatus) { record("starting " + s.Name) },
+		TaskStopped:  func(s *TasksStatus) { record("stopped " + s.Name) },
+		TaskFailed: func(s *TasksStatus, err error) {
+			if errors.Is(err, failed) && !s.Running {
+				record("failed " + s.Name)
+			}
+		},
+	}
+
+	r.Add(&funcTask{name: "a", run: func(ctx context.Context) error {
+		return failed
+	}})
+
+	_ = r.Run(context.Background())
+
+	want := []string{"starting a", "failed a"}
+	if !slices.Equal(events, want) {
+		t.Fatalf("events = %v, want %v", events, want)
+	}
+
+	events = nil
+
+	r = &Runner{
+		TaskStarting: r.TaskStarting,
+		TaskStopped:  r.TaskStopped,
+	}
+	r.Add(&funcTask{name: "b", run: func(ctx context.Context) error {
+		return nil
+	}})
+
+	_ = r.Run(context.Background())
+
+	want = []string{"starting b", "stopped b"}
+	if !slices.Equal(events, want) {
+		t.Fatalf("events = %v, want %v", events, want)
+	}
+}
+
+func TestRunnerRemove(t *testing.T) {
+	r := &Runner{}
+
+	a := blockingTask("a")
+	r.Add(a)
+	r.Add(blockingTask("b"))
+
+	stopped := startRunner(t, r)
+
+	// a Task canceled by Remove has stopped gracefully.
+	r.Remove(a)
+
+	assertRunning(t, stopped)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if tasks := r.taskList(); len(tasks) != 1 || tasks[0].TaskName() != "b" {
+		t.Fatalf("tasks = %v, want b", tasks)
+	}
+}
+
+// restartTask is a RestartTask failing with err on its first fails runs, then
+// running until stopped.
+type restartTask struct {
+	name   string
+	policy *RestartPolicy
+	err    error
+	fails  int
+
+	runs atomic.Int32
+}
+
+func (t *restartTask) TaskName() string {
+	return t.name
+}
+
+func (t *restartTask) RestartPolicy() *RestartPolicy {
+	return t.policy
+}
+
+func (t *restartTask) RunTask(ctx context.Context) error {
+	if int(t.runs.Add(1)) <= t.fails {
+		return t.err
+	}
+
+	<-ctx.Done()
+
+	return ctx.Err()
+}
+
+func TestRunnerRestarts(t *testing.T) {
+	failed := errors.New("failed")
+
+	task := &restartTask{
+		name: "a",
+		policy: &RestartPolicy{
+			Restart:    RestartOnFailure,
+			Backoff:    10 * time.Millisecond,
+			MaxBackoff: time.Second,
+		},
+		err:   failed,
+		fails: 3,
+	}
+
+	var mu sync.Mutex
+	delays := []time.Duration{}
+
+	r := &Runner{
+		TaskRestarting: func(s *TasksStatus, err error, delay time.Duration) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if errors.Is(err, failed) && s.Restarts == len(delays) {
+				delays = append(delays, delay)
+			}
+		},
+	}
+	r.Add(task)
+
+	stopped := startRunner(t, r)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for task.runs.Load() < 4 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assertRunning(t, stopped)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	want := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond}
+	if !slices.Equal(delays, want) {
+		t.Fatalf("delays = %v, want %v", delays, want)
+	}
+}
+
+func TestRunnerRestartLimit(t *testing.T) {
+	failed := errors.New("failed")
+
+	task := &restartTask{
+		name: "a",
+		policy: &RestartPolicy{
+			Restart:     RestartOnFailure,
+			MaxRestarts: 2,
+			Window:      time.Minute,
+		},
+		err:   failed,
+		fails: 10,
+	}
+
+	r := &Runner{}
+	r.Add(task)
+	r.Add(blockingTask("b"))
+
+	err := r.Run(context.Background())
+
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Name != "a" || !errors.Is(err, failed) {
+		t.Fatalf("err = %v, want the TaskError of a", err)
+	}
+
+	if !strings.Contains(err.Error(), "restarted 2 times within 1m0s") {
+		t.Errorf("err = %v, want the restart limit", err)
+	}
+
+	if runs := task.runs.Load(); runs != 3 {
+		t.Errorf("runs = %d, want 3", runs)
+	}
+}
+
+func TestRunnerRestartAlways(t *testing.T) {
+	task := &restartTask{
+		name:   "a",
+		policy: &RestartPolicy{Restart: RestartAlways},
+		fails:  2,
+	}
+
+	r := &Runner{}
+	r.Add(task)
+
+	stopped := startRunner(t, r)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for task.runs.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assertRunning(t, stopped)
+
+	if runs := task.runs.Load(); runs != 3 {
+		t.Errorf("runs = %d, want restarted after stopping without an error", runs)
+	}
+}
+
+func TestRunnerRestartNever(t *testing.T) {
+	task := &restartTask{
+		name:   "a",
+		policy: &RestartPolicy{Restart: RestartNever},
+		err:    errors.New("failed"),
+		fails:  1,
+	}
+
+	r := &Runner{}
+	r.Add(task)
+
+	err := r.Run(context.Background())
+	if err == nil || task.runs.Load() != 1 {
+		t.Fatalf("err = %v, runs = %d, want failed without restarting", err, task.runs.Load())
+	}
+}
@@ -0,0 +1,377 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/svalevka/go/pkg/log"
+)
+
+// Strategies of a Supervisor, named after those of Erlang supervisors.
+const (
+	// OneForOne restarts only the Task that stopped.
+	OneForOne = "one-for-one"
+
+	// OneForAll restarts every running Task when one stops.
+	OneForAll = "one-for-all"
+
+	// RestForOne restarts the Task that stopped, and the running Tasks added
+	// after it.
+	RestForOne = "rest-for-one"
+)
+
+// Supervisor is a Task running a group of Tasks, which restarts them when they
+// stop according to its Strategy, so coupled Tasks recover together and
+// independently of other groups. Supervisors can be nested, where a Supervisor
+// restarting its Tasks more often than its Intensity stops them and fails,
+// escalating to its parent.
+//
+// Tasks are restarted by their RestartPolicy if they are a RestartTask, or
+// otherwise when they fail, after the backoff of the Task that stopped. The
+// Tasks are started together, without waiting for readiness or dependencies,
+// and are logged using the logger of the context, see log.FromContext, with
+// the supervisor attribute naming the innermost Supervisor of the Task.
+type Supervisor struct {
+	// Name identifies this Task in the TaskStatus calls from the Task Runner.
+	Name string
+
+	// Strategy is which Tasks are restarted when one stops, OneForOne if
+	// empty.
+	Strategy string
+
+	// Intensity is the number of restarts allowed within Period, after which
+	// the Supervisor fails, or unlimited if zero.
+	Intensity int
+
+	// Period is the period Intensity is counted over, or 10m if zero.
+	Period time.Duration
+
+	// Dependencies are optionally the Tasks of the Runner the Tasks of the
+	// Supervisor depend on, so it starts once they are ready, and stops
+	// before them.
+	Dependencies []Task
+
+	mu       sync.Mutex
+	children []*child
+
+	// ctx, cancel, wg, intensity and err are set while the Supervisor is
+	// running.
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        *sync.WaitGroup
+	intensity *restarter
+	err       error
+}
+
+// child tracks a Task added to a Supervisor.
+type child struct {
+	task      Task
+	restarter *restarter
+	restarts  int
+
+	running bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	// stopping is set while the Task is stopped by the Supervisor, so it
+	// isn't handled as having stopped by itself.
+	stopping bool
+}
+
+// DependsOn returns the Dependencies of the Supervisor.
+func (s *Supervisor) DependsOn() []Task {
+	return s.Dependencies
+}
+
+func (s *Supervisor) TaskName() string {
+	return "Supervisor(" + s.Name + ")"
+}
+
+// Add attaches a Task to the Supervisor, which is started immediately if the
+// Supervisor is running.
+func (s *Supervisor) Add(t Task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := &child{task: t, restarter: newRestarter(t)}
+	s.children = append(s.children, c)
+
+	if s.ctx != nil {
+		s.start(c)
+	}
+}
+
+// Remove detaches a Task from the Supervisor, canceling its context if it is
+// running and waiting for it to stop.
+func (s *Supervisor) Remove(t Task) {
+	s.mu.Lock()
+
+	idx := slices.IndexFunc(s.children, func(c *child) bool { return c.task == t })
+	if idx < 0 {
+		s.mu.Unlock()
+		return
+	}
+
+	c := s.children[idx]
+	s.children = slices.Delete(s.children, idx, idx+1)
+
+	s.mu.Unlock()
+
+	s.stop(c)
+}
+
+// Replace swaps a Task of the Supervisor for another in the same position,
+// starting the new Task before stopping the old one. The new Task is added if
+// the old one isn't found.
+func (s *Supervisor) Replace(old, new Task) {
+	s.mu.Lock()
+
+	idx := slices.IndexFunc(s.children, func(c *child) bool { return c.task == old })
+	if idx < 0 {
+		s.mu.Unlock()
+		s.Add(new)
+		return
+	}
+
+	c := s.children[idx]
+	s.children[idx] = &child{task: new, restarter: newRestarter(new)}
+
+	if s.ctx != nil {
+		s.start(s.children[idx])
+	}
+
+	s.mu.Unlock()
+
+	s.stop(c)
+}
+
+func (s *Supervisor) RunTask(ctx context.Context) error {
+	s.mu.Lock()
+
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.wg = new(sync.WaitGroup)
+	s.err = nil
+
+	// the restart type of each Task is checked first, so the intensity only
+	// counts restarts.
+	period := s.Period
+	if period == 0 {
+		period = 10 * time.Minute
+	}
+
+	s.intensity = &restarter{policy: &RestartPolicy{
+		Restart:     RestartAlways,
+		MaxRestarts: s.Intensity,
+		Window:      period,
+	}}
+
+	for _, c := range s.children {
+		c.restarter = newRestarter(c.task)
+		s.start(c)
+	}
+
+	sctx := s.ctx
+
+	s.mu.Unlock()
+
+	// the Supervisor runs until stopped or it fails, even once its Tasks
+	// have stopped, so Tasks can still be added.
+	<-sctx.Done()
+
+	s.wg.Wait()
+
+	s.mu.Lock()
+	s.cancel()
+	s.ctx, s.cancel = nil, nil
+	err := s.err
+	s.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// newRestarter returns the restarter of a Task, using its RestartPolicy if it
+// is a RestartTask, or the default RestartPolicy otherwise, which restarts it
+// on failure.
+func newRestarter(t Task) *restarter {
+	if rt, ok := t.(RestartTask); ok {
+		return &restarter{policy: rt.RestartPolicy()}
+	}
+
+	return &restarter{policy: defaultRestartPolicy()}
+}
+
+// start runs a Task in a new goroutine with its own cancelable context, the
+// Supervisor must be locked.
+func (s *Supervisor) start(c *child) {
+	ctx, cancel := context.WithCancel(log.With(s.ctx, slog.String("supervisor", s.TaskName()), slog.String("task", c.task.TaskName())))
+
+	c.running, c.stopping = true, false
+	c.cancel = cancel
+	c.done = make(chan struct{})
+
+	s.wg.Add(1)
+
+	go func(ctx context.Context, c *child, done chan struct{}) {
+		defer s.wg.Done()
+
+		log.FromContext(ctx).Info("task starting...", slog.Int("restarts", c.restarts))
+
+		started := time.Now()
+		err := c.task.RunTask(ctx)
+
+		s.mu.Lock()
+		c.running = false
+		s.mu.Unlock()
+
+		cancel()
+		close(done)
+
+		// a Task returning because it was asked to stop has stopped
+		// gracefully.
+		if errors.Is(err, context.Canceled) && ctx.Err() != nil {
+			err = nil
+		}
+
+		s.exited(ctx, c, err, time.Since(started))
+	}(ctx, c, c.done)
+}
+
+// stop cancels a Task and waits for it to stop.
+func (s *Supervisor) stop(c *child) {
+	s.mu.Lock()
+
+	done := c.done
+	if c.running {
+		c.stopping = true
+		c.cancel()
+	}
+
+	s.mu.Unlock()
+
+	if done != nil {
+		<-done
+	}
+}
+
+// exited handles a Task that returned err after running for ran, restarting
+// it and the Tasks of its Strategy, or failing the Supervisor if it restarts
+// them too often.
+func (s *Supervisor) exited(ctx context.Context, c *child, err error, ran time.Duration) {
+	logger := log.FromContext(ctx)
+
+	if err != nil {
+		logger.Error("task failed", slog.String("error", err.Error()))
+	} else {
+		logger.Info("task stopped")
+	}
+
+	s.mu.Lock()
+
+	// Tasks stopped by the Supervisor, or removed from it, aren't restarted
+	// by themselves.
+	if c.stopping || s.ctx == nil || s.ctx.Err() != nil || !slices.Contains(s.children, c) {
+		s.mu.Unlock()
+		return
+	}
+
+	delay, restart, limitErr := c.restarter.next(err, ran)
+	if restart {
+		_, _, limitErr = s.intensity.next(err, 0)
+	}
+
+	if limitErr != nil {
+		if err != nil {
+			limitErr = fmt.Errorf("%w: %w", limitErr, err)
+		}
+
+		s.err = &TaskError{Name: c.task.TaskName(), Err: limitErr}
+		s.cancel()
+		s.mu.Unlock()
+
+		return
+	}
+
+	if !restart {
+		s.mu.Unlock()
+		return
+	}
+
+	group := s.group(c)
+	for _, g := range group {
+		if g != c {
+			g.stopping = true
+			g.cancel()
+		}
+	}
+
+	sctx := s.ctx
+
+	s.mu.Unlock()
+
+	for _, g := range group {
+		<-g.done
+	}
+
+	attrs := []any{slog.Int("restarts", c.restarts), slog.Duration("delay", delay)}
+	if len(group) > 1 {
+		attrs = append(attrs, slog.Int("tasks", len(group)))
+	}
+
+	logger.Warn("task restarting...", attrs...)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-sctx.Done():
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ctx != sctx || sctx.Err() != nil {
+		return
+	}
+
+	for _, g := range group {
+		// Tasks removed or already restarted in the meantime are skipped.
+		if g.running || !slices.Contains(s.children, g) {
+			continue
+		}
+
+		g.restarts++
+		s.start(g)
+	}
+}
+
+// group returns the Tasks restarted by the Strategy when c stops, being c and
+// the Tasks still running, the Supervisor must be locked.
+func (s *Supervisor) group(c *child) []*child {
+	group := []*child{}
+
+	after := false
+
+	for _, g := range s.children {
+		switch {
+		case g == c:
+			group = append(group, g)
+			after = true
+		case !g.running:
+		case s.Strategy == OneForAll, s.Strategy == RestForOne && after:
+			group = append(group, g)
+		}
+	}
+
+	return group
+}
@@ -0,0 +1,296 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/svalevka/go/pkg/log"
+)
+
+// childTask is a RestartTask restarted immediately on failure, which runs
+// until stopped or failed with an error given to fail.
+type childTask struct {
+	name string
+	fail chan error
+	runs atomic.Int32
+}
+
+func newChildTask(name string) *childTask {
+	return &childTask{name: name, fail: make(chan error)}
+}
+
+func (c *childTask) TaskName() string {
+	return c.name
+}
+
+func (c *childTask) RestartPolicy() *RestartPolicy {
+	return &RestartPolicy{Restart: RestartOnFailure}
+}
+
+func (c *childTask) RunTask(ctx context.Context) error {
+	c.runs.Add(1)
+
+	select {
+	case err := <-c.fail:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// waitRuns waits for each of children to have run the number of times given
+// in order by runs.
+func waitRuns(t *testing.T, children []*childTask, runs ...int32) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for i, c := range children {
+		for c.runs.Load() < runs[i] {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s ran %d times, want %d", c.name, c.runs.Load(), runs[i])
+			}
+
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	// restarts beyond those expected are given a moment to happen.
+	time.Sleep(50 * time.Millisecond)
+
+	for i, c := range children {
+		if got := c.runs.Load(); got != runs[i] {
+			t.Errorf("%s ran %d times, want %d", c.name, got, runs[i])
+		}
+	}
+}
+
+// runSupervisor runs s until the test ends, returning the channel given the
+// error of RunTask.
+func runSupervisor(t *testing.T, ctx context.Context, s *Supervisor) chan error {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	stopped := make(chan error, 1)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		stopped <- s.RunTask(ctx)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Error("supervisor didn't stop")
+		}
+	})
+
+	return stopped
+}
+
+// quietContext returns a context whose logger discards the logs of Tasks.
+func quietContext() context.Context {
+	return log.NewContext(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestSupervisorStrategies(t *testing.T) {
+	tests := []struct {
+		strategy string
+		runs     []int32
+	}{
+		{OneForOne, []int32{1, 2, 1}},
+		{OneForAll, []int32{2, 2, 2}},
+		{RestForOne, []int32{1, 2, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			children := []*childTask{newChildTask("a"), newChildTask("b"), newChildTask("c")}
+
+			s := &Supervisor{Name: "test", Strategy: tt.strategy}
+			for _, c := range children {
+				s.Add(c)
+			}
+
+			runSupervisor(t, quietContext(), s)
+			waitRuns(t, children, 1, 1, 1)
+
+			children[1].fail <- errors.New("failed")
+
+			waitRuns(t, children, tt.runs...)
+		})
+	}
+}
+
+func TestSupervisorStoppedTask(t *testing.T) {
+	a, b := newChildTask("a"), newChildTask("b")
+
+	s := &Supervisor{Name: "test", Strategy: OneForAll}
+	s.Add(a)
+	s.Add(b)
+
+	runSupervisor(t, quietContext(), s)
+	waitRuns(t, []*childTask{a, b}, 1, 1)
+
+	// a Task stopping without an error isn't restarted by on-failure, nor
+	// are the others.
+	a.fail <- nil
+
+	waitRuns(t, []*childTask{a, b}, 1, 1)
+}
+
+func TestSupervisorEscalates(t *testing.T) {
+	a := newChildTask("a")
+
+	s := &Supervisor{Name: "test", Intensity: 2, Period: time.Minute}
+	s.Add(a)
+
+	stopped := runSupervisor(t, quietContext(), s)
+
+	failed := errors.New("failed")
+	for i := 0; i < 3; i++ {
+		a.fail <- failed
+	}
+
+	select {
+	case err := <-stopped:
+		var taskErr *TaskError
+		if !errors.As(err, &taskErr) || taskErr.Name != "a" || !errors.Is(err, failed) {
+			t.Fatalf("err = %v, want the TaskError of a", err)
+		}
+
+		if !strings.Contains(err.Error(), "restarted 2 times") {
+			t.Errorf("err = %v, want the intensity exceeded", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("supervisor didn't fail")
+	}
+}
+
+func TestSupervisorNested(t *testing.T) {
+	a := newChildTask("a")
+
+	inner := &Supervisor{Name: "inner", Intensity: 1, Period: time.Minute}
+	inner.Add(a)
+
+	sibling := newChildTask("sibling")
+
+	outer := &Supervisor{Name: "outer", Strategy: OneForOne}
+	outer.Add(sibling)
+	outer.Add(inner)
+
+	runSupervisor(t, quietContext(), outer)
+	waitRuns(t, []*childTask{a, sibling}, 1, 1)
+
+	// the inner Supervisor fails once a restarts too often, so is
+	// restarted by the outer Supervisor, restarting a without its
+	// sibling.
+	a.fail <- errors.New("failed")
+	waitRuns(t, []*childTask{a, sibling}, 2, 1)
+
+	a.fail <- errors.New("failed")
+	waitRuns(t, []*childTask{a, sibling}, 3, 1)
+}
+
+func TestSupervisorChanges(t *testing.T) {
+	a, b, c := newChildTask("a"), newChildTask("b"), newChildTask("c")
+
+	s := &Supervisor{Name: "test", Strategy: RestForOne}
+	s.Add(a)
+
+	runSupervisor(t, quietContext(), s)
+
+	s.Add(b)
+	waitRuns(t, []*childTask{a, b}, 1, 1)
+
+	// c takes the position of a, so is followed by b.
+	s.Replace(a, c)
+	waitRuns(t, []*childTask{a, b, c}, 1, 1, 1)
+
+	c.fail <- errors.New("failed")
+	waitRuns(t, []*childTask{a, b, c}, 1, 2, 2)
+
+	s.Remove(b)
+
+	c.fail <- errors.New("failed")
+	waitRuns(t, []*childTask{a, b, c}, 1, 2, 3)
+}
+
+// syncBuffer is a bytes.Buffer safe to write to from multiple Tasks.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return bytes.Clone(b.buf.Bytes())
+}
+
+func TestSupervisorLogAttrs(t *testing.T) {
+	buf := &syncBuffer{}
+	logger := slog.New(slog.NewJSONHandler(buf, nil))
+
+	a := newChildTask("a")
+
+	inner := &Supervisor{Name: "inner"}
+	inner.Add(a)
+
+	outer := &Supervisor{Name: "outer"}
+	outer.Add(inner)
+
+	ctx := log.With(log.NewContext(context.Background(), logger), slog.String("task", outer.TaskName()))
+
+	runSupervisor(t, ctx, outer)
+	waitRuns(t, []*childTask{a}, 1)
+
+	found := false
+
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		if n := bytes.Count(line, []byte(`"task"`)); n != 1 {
+			t.Errorf("task written %d times: %s", n, line)
+		}
+
+		record := map[string]any{}
+
+		err := json.Unmarshal(line, &record)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if record["task"] == "a" {
+			found = true
+
+			if record["supervisor"] != inner.TaskName() {
+				t.Errorf("supervisor = %v, want %s", record["supervisor"], inner.TaskName())
+			}
+		}
+	}
+
+	if !found {
+		t.Errorf("no logs of a: %s", buf.Bytes())
+	}
+}
@@ -18,6 +18,25 @@ fi
 # build the binary.
 ./scripts/build.sh $SERVICE
 
+echo "» generating configuration schema and example for $SERVICE..."
+
+# generate the json schema of the configuration file for editors.
+"svc/$SERVICE/build/dist/$SERVICE" -print-config-schema > "svc/$SERVICE/build/dist/config.schema.json"
+
+# generate the example configuration file installed by the package, pointing
+# editors at the installed schema.
+{
+	echo "# yaml-language-server: \$schema=/usr/share/$SERVICE/config.schema.json"
+	"svc/$SERVICE/build/dist/$SERVICE" -print-default-config
+} > "svc/$SERVICE/build/dist/config.example.yml"
+
+# exit if the example configuration doesn't load, as the package would install
+# a configuration the service can't start with.
+if ! "svc/$SERVICE/build/dist/$SERVICE" -config "svc/$SERVICE/build/dist/config.example.yml" -print-config > /dev/null; then
+	echo "» example configuration for $SERVICE is invalid, exiting..."
+	exit 1
+fi
+
 echo "» building package for $SERVICE..."
 
 # build the package.
@@ -2,46 +2,184 @@ package v1service
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"reflect"
+	"time"
 
+	"github.com/svalevka/go/pkg/config"
 	"github.com/svalevka/go/pkg/config/common"
 	"github.com/svalevka/go/pkg/service"
+	"github.com/svalevka/go/pkg/tasks"
 )
 
+//go:generate go run github.com/svalevka/go/pkg/config/docgen
+
+// Config contains the configuration of the streams monitored by
+// nats-jetstream-statsd and where their metrics are written.
 type Config struct {
 	common.Logs `yaml:"logs"`
 
-	Streams []*Stream `yaml:"streams"`
+	// Admin configures the administrative HTTP server, used to change log
+	// levels at runtime.
+	Admin common.Admin `yaml:"admin"`
+
+	// Streams are the NATS JetStream streams to monitor, with the NATS
+	// connection used to receive their advisories.
+	Streams []*Stream `yaml:"streams" validate:"required"`
+
+	// StatsD configures where metrics are written.
+	StatsD *common.StatsD `yaml:"statsd" default:"{}" validate:"required"`
+}
+
+// GetAdmin returns the configuration of the administrative HTTP server.
+func (c *Config) GetAdmin() *common.Admin {
+	return &c.Admin
+}
+
+// Validate ensures stream names are unique, as they identify the tasks
+// monitoring each stream.
+func (c *Config) Validate() error {
+	names := map[string]int{}
+
+	for idx, stream := range c.Streams {
+		if first, ok := names[stream.Name]; ok {
+			return (&config.ValidationError{
+				Field:   "name",
+				Message: fmt.Sprintf("duplicate stream name %q, first used by streams[%d]", stream.Name, first),
+			}).WrapIdx("streams", idx)
+		}
+
+		names[stream.Name] = idx
+	}
 
-	StatsD *StatsD `yaml:"statsd"`
+	return nil
 }
 
+// Stream configures the monitoring of a single NATS JetStream stream.
 type Stream struct {
-	Name string `yaml:"name"`
+	// Name identifies the stream in logs, and must be unique.
+	Name string `yaml:"name" example:"orders" validate:"required"`
 
+	// NATS configures the connection to the NATS account of the stream.
 	NATS common.NATS `yaml:"nats"`
-}
 
-type StatsD struct {
-	Host string `yaml:"host"`
+	// Restart configures how monitoring the stream is restarted when it
+	// fails, such as while NATS is unavailable.
+	Restart tasks.RestartPolicy `yaml:"restart"`
 }
 
 func New(ctx context.Context, svc *service.Runner, cfg *Config) error {
-	events := make(chan *Event, 1024)
+	m := &monitor{
+		svc:     svc,
+		events:  make(chan *Event, 1024),
+		streams: map[string]*Events{},
 
-	for _, stream := range cfg.Streams {
-		svc.Tasks.Add(&Events{
-			Stream: stream,
-			Target: events,
-			Logger: svc.Logger.With(slog.String("task", "Events"), slog.String("stream", stream.Name)),
-		})
+		// streams of the same NATS account share a connection.
+		pool: &common.NATSPool{
+			ClientName: "nats-jetstream-statsd.v1",
+			Logger:     svc.Logger.With(slog.String("task", "NATSPool")),
+		},
+
+		supervisor: &tasks.Supervisor{
+			Name:      "Monitor",
+			Strategy:  tasks.RestForOne,
+			Intensity: monitorIntensity,
+			Period:    monitorPeriod,
+		},
+		streamsSupervisor: &tasks.Supervisor{
+			Name:     "Streams",
+			Strategy: tasks.OneForOne,
+		},
 	}
 
-	svc.Tasks.Add(&Stats{
-		StatsD: cfg.StatsD,
-		Source: events,
-		Logger: svc.Logger.With(slog.String("task", "Stats")),
+	// the connections are drained once the tasks using them have stopped.
+	m.supervisor.Dependencies = []tasks.Task{m.pool}
+
+	svc.Tasks.Add(m.pool)
+
+	// the Stats sink must run for the Events tasks to make progress, as
+	// they block on the shared channel, so they are restarted with it,
+	// while each stream otherwise recovers separately.
+	m.apply(cfg)
+
+	m.supervisor.Add(m.streamsSupervisor)
+	svc.Tasks.Add(m.supervisor)
+
+	service.OnReload(svc, func(ctx context.Context, cfg *Config) error {
+		m.apply(cfg)
+		return nil
 	})
 
 	return nil
 }
+
+// Restart intensity of the Monitor supervisor, after which the service fails.
+const (
+	monitorIntensity = 5
+	monitorPeriod    = 10 * time.Minute
+)
+
+// monitor tracks the tasks running for the current configuration, so they can
+// be replaced when the configuration is reloaded. The Stats task is supervised
+// before the Streams supervisor of the Events tasks, so restarting it restarts
+// them too.
+type monitor struct {
+	svc    *service.Runner
+	events chan *Event
+	pool   *common.NATSPool
+
+	supervisor        *tasks.Supervisor
+	streamsSupervisor *tasks.Supervisor
+
+	streams map[string]*Events
+	stats   *Stats
+}
+
+// apply starts tasks for new or changed streams and StatsD configuration, and
+// stops tasks for streams that were removed or changed.
+func (m *monitor) apply(cfg *Config) {
+	if m.stats == nil || !reflect.DeepEqual(m.stats.StatsD, cfg.StatsD) {
+		// start the new sink before stopping the old one, so the events
+		// tasks are never left without a reader.
+		old := m.stats
+
+		m.stats = &Stats{
+			StatsD: cfg.StatsD,
+			Source: m.events,
+			Pool:   m.pool,
+			Logger: m.svc.Logger.With(slog.String("task", "Stats")),
+		}
+		m.supervisor.Replace(old, m.stats)
+	}
+
+	configured := map[string]bool{}
+
+	for _, stream := range cfg.Streams {
+		configured[stream.Name] = true
+
+		existing, ok := m.streams[stream.Name]
+		if ok && reflect.DeepEqual(existing.Stream, stream) {
+			continue
+		}
+
+		if ok {
+			m.streamsSupervisor.Remove(existing)
+		}
+
+		m.streams[stream.Name] = &Events{
+			Stream: stream,
+			Target: m.events,
+			Pool:   m.pool,
+			Logger: m.svc.Logger.With(slog.String("task", "Events"), slog.String("stream", stream.Name)),
+		}
+		m.streamsSupervisor.Add(m.streams[stream.Name])
+	}
+
+	for name, events := range m.streams {
+		if !configured[name] {
+			m.streamsSupervisor.Remove(events)
+			delete(m.streams, name)
+		}
+	}
+}
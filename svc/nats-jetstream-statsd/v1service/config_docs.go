@@ -0,0 +1,18 @@
+// Code generated by docgen. DO NOT EDIT.
+
+package v1service
+
+import "github.com/svalevka/go/pkg/config"
+
+func init() {
+	config.RegisterDocs("github.com/svalevka/go/svc/nats-jetstream-statsd/v1service", map[string]string{
+		"Config":         "Config contains the configuration of the streams monitored by nats-jetstream-statsd and where their metrics are written.",
+		"Config.Admin":   "Admin configures the administrative HTTP server, used to change log levels at runtime.",
+		"Config.StatsD":  "StatsD configures where metrics are written.",
+		"Config.Streams": "Streams are the NATS JetStream streams to monitor, with the NATS connection used to receive their advisories.",
+		"Stream":         "Stream configures the monitoring of a single NATS JetStream stream.",
+		"Stream.NATS":    "NATS configures the connection to the NATS account of the stream.",
+		"Stream.Name":    "Name identifies the stream in logs, and must be unique.",
+		"Stream.Restart": "Restart configures how monitoring the stream is restarted when it fails, such as while NATS is unavailable.",
+	})
+}
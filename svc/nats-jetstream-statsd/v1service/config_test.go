@@ -0,0 +1,28 @@
+package v1service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/svalevka/go/pkg/config"
+)
+
+func TestDefaultConfigLoads(t *testing.T) {
+	b, err := config.RenderDefaults(&Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yml")
+
+	err = os.WriteFile(path, b, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = config.FromFile(path, &Config{})
+	if err != nil {
+		t.Fatalf("default configuration doesn't load: %v\n%s", err, b)
+	}
+}
@@ -9,6 +9,9 @@ import (
 	"github.com/nats-io/jsm.go/api/jetstream/advisory"
 	server "github.com/nats-io/jsm.go/api/server/advisory"
 	"github.com/nats-io/nats.go"
+
+	"github.com/svalevka/go/pkg/config/common"
+	"github.com/svalevka/go/pkg/tasks"
 )
 
 // Event contains the metadata unmarshaled from the JetStream
@@ -30,6 +33,10 @@ type Events struct {
 
 	Target chan *Event
 
+	// Pool provides the connection to NATS, shared with other streams of the
+	// same NATS account.
+	Pool *common.NATSPool
+
 	// Logger optionally configures the logger where debug information is
 	// written to.
 	Logger *slog.Logger
@@ -39,12 +46,18 @@ func (e *Events) TaskName() string {
 	return "Events(" + e.Stream.Name + ", " + e.Stream.NATS.Username + ")"
 }
 
+// RestartPolicy restarts the Task when it fails, such as while NATS is
+// unavailable, as configured by the Stream.
+func (e *Events) RestartPolicy() *tasks.RestartPolicy {
+	return &e.Stream.Restart
+}
+
 func (e *Events) RunTask(ctx context.Context) error {
-	conn, err := e.Stream.NATS.Connect("nats-jetstream-statsd.v1")
+	conn, err := e.Pool.Get(ctx, &e.Stream.NATS)
 	if err != nil {
 		return fmt.Errorf("nats: %w", err)
 	}
-	defer conn.Close()
+	defer e.Pool.Release(conn)
 
 	msgs := make(chan *nats.Msg, 1024)
 
@@ -54,10 +67,22 @@ func (e *Events) RunTask(ctx context.Context) error {
 	}
 	defer sub.Unsubscribe()
 
+	// the connection is closed once it fails to reconnect, when the Task
+	// fails so it is restarted with a new connection.
+	closed := e.Pool.Closed(conn)
+
 	for {
 		select {
 		case msg := <-msgs:
-			e.handleMsg(msg)
+			e.handleMsg(ctx, msg)
+
+		case <-closed:
+			err := conn.LastError()
+			if err == nil {
+				err = nats.ErrConnectionClosed
+			}
+
+			return fmt.Errorf("nats: %w", err)
 
 		case <-ctx.Done():
 			return ctx.Err()
@@ -65,7 +90,7 @@ func (e *Events) RunTask(ctx context.Context) error {
 	}
 }
 
-func (e *Events) handleMsg(msg *nats.Msg) {
+func (e *Events) handleMsg(ctx context.Context, msg *nats.Msg) {
 	kind, body, _ := api.ParseMessage(msg.Data)
 
 	e.Logger.Debug("jetstream event advisory received", slog.String("kind", kind))
@@ -83,9 +108,11 @@ func (e *Events) handleMsg(msg *nats.Msg) {
 
 	switch body := body.(type) {
 	case *api.JSApiConsumerInfoResponse:
-		e.Target <- &Event{
-			Client:   &event.Client,
-			Consumer: body,
+		// don't block forever if the task is stopped while the Stats task
+		// is not reading events.
+		select {
+		case e.Target <- &Event{Client: &event.Client, Consumer: body}:
+		case <-ctx.Done():
 		}
 	}
 }
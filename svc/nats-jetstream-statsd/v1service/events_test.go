@@ -0,0 +1,60 @@
+package v1service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/svalevka/go/pkg/config/common"
+	"github.com/svalevka/go/pkg/config/common/natstest"
+)
+
+func TestEventsConnectionClosed(t *testing.T) {
+	stub := natstest.NewServer(t)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	e := &Events{
+		Stream: &Stream{
+			Name: "orders",
+			NATS: common.NATS{
+				Servers:       []string{stub.URL()},
+				MaxReconnects: 0,
+				Timeout:       time.Second,
+				DrainTimeout:  time.Second,
+			},
+		},
+		Target: make(chan *Event),
+		Pool:   &common.NATSPool{ClientName: "test", Logger: logger},
+		Logger: logger,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- e.RunTask(ctx)
+	}()
+
+	// the connection closes without reconnecting once it is subscribed.
+	deadline := time.After(5 * time.Second)
+
+	for {
+		stub.Disconnect()
+
+		select {
+		case err := <-stopped:
+			if err == nil || !strings.HasPrefix(err.Error(), "nats: ") {
+				t.Errorf("err = %v, want the connection closed", err)
+			}
+
+			return
+		case <-time.After(20 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("task didn't fail once the connection closed")
+		}
+	}
+}
@@ -4,19 +4,30 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/DataDog/datadog-go/v5/statsd"
+
+	"github.com/svalevka/go/pkg/config/common"
 )
 
+// poolStatsInterval is how often the statistics of NATS connections are
+// written.
+const poolStatsInterval = 10 * time.Second
+
 // Stats is a task that reads events generated by the Events task and writes
 // them to StatsD.
 type Stats struct {
-	// StatsD configures the StatsD host where metrics are written.
-	StatsD *StatsD
+	// StatsD configures the StatsD client where metrics are written.
+	StatsD *common.StatsD
 
 	// Source configures the channel where the Events task writes it's events.
 	Source chan *Event
 
+	// Pool optionally provides the NATS connections whose statistics are
+	// written periodically.
+	Pool *common.NATSPool
+
 	// Logger optionally configures the logger where debug information is
 	// written to.
 	Logger *slog.Logger
@@ -27,17 +38,23 @@ func (s *Stats) TaskName() string {
 }
 
 func (s *Stats) RunTask(ctx context.Context) error {
-	stats, err := statsd.New(s.StatsD.Host, statsd.WithoutTelemetry())
+	stats, err := s.StatsD.Client()
 	if err != nil {
 		return fmt.Errorf("statsd: %w", err)
 	}
 	defer stats.Close()
 
+	ticker := time.NewTicker(poolStatsInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case event := <-s.Source:
 			s.handleEvent(stats, event)
 
+		case <-ticker.C:
+			s.writePoolStats(stats)
+
 		case <-ctx.Done():
 			return ctx.Err()
 		}
@@ -62,3 +79,23 @@ func (s *Stats) handleEvent(stats *statsd.Client, event *Event) {
 
 	s.Logger.Debug("stats written", slog.Any("tags", tags))
 }
+
+func (s *Stats) writePoolStats(stats *statsd.Client) {
+	if s.Pool == nil {
+		return
+	}
+
+	for _, conn := range s.Pool.Stats() {
+		tags := []string{
+			"nats_connection:" + conn.Fingerprint,
+			"nats_status:" + conn.Status,
+		}
+
+		stats.Gauge("nats.client.refs", float64(conn.Refs), tags, 1)
+		stats.Gauge("nats.client.in_msgs", float64(conn.InMsgs), tags, 1)
+		stats.Gauge("nats.client.out_msgs", float64(conn.OutMsgs), tags, 1)
+		stats.Gauge("nats.client.in_bytes", float64(conn.InBytes), tags, 1)
+		stats.Gauge("nats.client.out_bytes", float64(conn.OutBytes), tags, 1)
+		stats.Gauge("nats.client.reconnects", float64(conn.Reconnects), tags, 1)
+	}
+}
@@ -6,6 +6,8 @@ import (
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/svalevka/go/pkg/log"
+	"github.com/svalevka/go/pkg/net/http/api"
 	"github.com/svalevka/go/pkg/net/http/web/assets"
 	"github.com/svalevka/go/svc/systemd-service-ui/v1service/views"
 )
@@ -101,9 +103,15 @@ func (a *App) ServiceAction(w http.ResponseWriter, r *http.Request) (views.View,
 
 func (a *App) handle(fn func(w http.ResponseWriter, r *http.Request) (views.View, error)) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		id := api.RequestID(r)
+		w.Header().Set(api.RequestIDHeader, id)
+
+		r = r.WithContext(log.With(r.Context(), slog.String("request_id", id)))
+		logger := a.Logger.With(slog.String("request_id", id))
+
 		view, err := fn(w, r)
 		if err != nil {
-			a.Logger.Error("an unexpected error occurred", slog.String("error", err.Error()))
+			logger.Error("an unexpected error occurred", slog.String("error", err.Error()))
 
 			view = &views.Error{
 				Status:  http.StatusInternalServerError,
@@ -114,7 +122,7 @@ func (a *App) handle(fn func(w http.ResponseWriter, r *http.Request) (views.View
 		if view != nil {
 			err = views.Render(w, r, view)
 			if err != nil {
-				a.Logger.Error("could not render template", slog.String("error", err.Error()))
+				logger.Error("could not render template", slog.String("error", err.Error()))
 			}
 		}
 	}
@@ -2,12 +2,17 @@ package v1service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"reflect"
 	"regexp"
 
 	"github.com/go-chi/chi/v5"
+	"gopkg.in/yaml.v3"
 
+	"github.com/svalevka/go/pkg/config"
 	"github.com/svalevka/go/pkg/config/common"
 	"github.com/svalevka/go/pkg/encoding"
 	"github.com/svalevka/go/pkg/net/http/api"
@@ -15,19 +20,106 @@ import (
 	"github.com/svalevka/go/pkg/tasks"
 )
 
+//go:generate go run github.com/svalevka/go/pkg/config/docgen
+
 // Config contains the configuration used to configure the systemd-service-ui
 // web application and server.
 type Config struct {
 	common.Logs `yaml:"logs"`
 
-	// Listen configures the host:port where the app will accept new
-	// connections.
-	Listen []string `yaml:"listen"`
+	// Admin configures the administrative HTTP server, used to change log
+	// levels at runtime.
+	Admin common.Admin `yaml:"admin"`
+
+	// Listen configures the HTTP servers accepting connections for the app,
+	// each with its own address, TLS and timeouts.
+	Listen []*common.HTTPServer `yaml:"listen" default:"[{addr: localhost:8080}]" validate:"required,min=1"`
+
+	// Services controls what services the systemd-service-ui is allowed to
+	// manage, no other services can be managed without this.
+	Services []*Service `yaml:"services" validate:"required,min=1"`
+}
+
+// GetAdmin returns the configuration of the administrative HTTP server.
+func (c *Config) GetAdmin() *common.Admin {
+	return &c.Admin
+}
+
+// Service configures services that may be managed.
+type Service struct {
+	// Pattern is a regular expression matching the names of the services.
+	Pattern string `yaml:"pattern" example:"^myservice-" validate:"required,regexp"`
+}
+
+// ConfigMigrations upgrades older versions of the configuration file.
+func (c *Config) ConfigMigrations() []config.Migration {
+	return []config.Migration{
+		migrateServices,
+		migrateListen,
+	}
+}
+
+// migrateServices upgrades version 1, where services were regular
+// expressions, to version 2, where they are Service entries.
+func migrateServices(root *yaml.Node) error {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "services" || root.Content[i+1].Kind != yaml.SequenceNode {
+			continue
+		}
+
+		items := root.Content[i+1].Content
+		for j, item := range items {
+			if item.Kind != yaml.ScalarNode {
+				return fmt.Errorf("services[%d]: expected a regular expression, or set version: 2 for a service entry", j)
+			}
 
-	// Services is an array of regular expression controlling what services
-	// the systemd-service-ui is allowed to manage, no other services can
-	// be managed without this.
-	Services []string `yaml:"services"`
+			items[j] = &yaml.Node{
+				Kind:        yaml.MappingNode,
+				HeadComment: item.HeadComment,
+				Content: []*yaml.Node{
+					{Kind: yaml.ScalarNode, Value: "pattern"},
+					item,
+				},
+			}
+
+			// the line comment is kept by the value, as the encoder misplaces
+			// those of mappings within sequences.
+			item.HeadComment = ""
+		}
+	}
+
+	return nil
+}
+
+// migrateListen upgrades version 2, where listen was a list of addresses, to
+// version 3, where it is a list of HTTP server configurations.
+func migrateListen(root *yaml.Node) error {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "listen" || root.Content[i+1].Kind != yaml.SequenceNode {
+			continue
+		}
+
+		items := root.Content[i+1].Content
+		for j, item := range items {
+			if item.Kind != yaml.ScalarNode {
+				return fmt.Errorf("listen[%d]: expected a host:port address, or set version: 3 for a server entry", j)
+			}
+
+			items[j] = &yaml.Node{
+				Kind:        yaml.MappingNode,
+				HeadComment: item.HeadComment,
+				Content: []*yaml.Node{
+					{Kind: yaml.ScalarNode, Value: "addr"},
+					item,
+				},
+			}
+
+			// the line comment is kept by the value, see migrateServices.
+			item.HeadComment = ""
+		}
+	}
+
+	return nil
 }
 
 // New initializes the service runner for the system.
@@ -37,15 +129,9 @@ func New(ctx context.Context, svc *service.Runner, cfg *Config) error {
 		return fmt.Errorf("could not get hostname: %w", err)
 	}
 
-	managed := []*regexp.Regexp{}
-
-	for _, expr := range cfg.Services {
-		re, err := regexp.Compile(expr)
-		if err != nil {
-			return fmt.Errorf("invalid regular expression %q: %w", expr, err)
-		}
-
-		managed = append(managed, re)
+	managed, err := compileServices(cfg.Services)
+	if err != nil {
+		return err
 	}
 
 	systemd, err := NewDbus(ctx, managed)
@@ -71,12 +157,122 @@ func New(ctx context.Context, svc *service.Runner, cfg *Config) error {
 	r.Route("/", app.Routes)
 	ra.Route("/api", rest.Routes)
 
-	for _, addr := range cfg.Listen {
-		svc.Tasks.Add(&tasks.HTTPServer{
+	l := &listeners{
+		svc:     svc,
+		handler: r,
+		servers: map[string]*tasks.HTTPServer{},
+	}
+
+	err = l.apply(cfg.Listen)
+	if err != nil {
+		return err
+	}
+
+	service.OnReload(svc, func(ctx context.Context, cfg *Config) error {
+		managed, err := compileServices(cfg.Services)
+		if err != nil {
+			return err
+		}
+
+		err = l.apply(cfg.Listen)
+		if err != nil {
+			return fmt.Errorf("listen: %w", err)
+		}
+
+		systemd.SetManaged(managed)
+
+		return nil
+	})
+
+	return nil
+}
+
+// compileServices compiles the regular expressions of managed services.
+func compileServices(services []*Service) ([]*regexp.Regexp, error) {
+	managed := []*regexp.Regexp{}
+
+	for _, svc := range services {
+		re, err := regexp.Compile(svc.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", svc.Pattern, err)
+		}
+
+		managed = append(managed, re)
+	}
+
+	return managed, nil
+}
+
+// listeners tracks the HTTP server task running for each listen address, so
+// they can be started and stopped when the configuration is reloaded.
+type listeners struct {
+	svc     *service.Runner
+	handler http.Handler
+	servers map[string]*tasks.HTTPServer
+}
+
+// apply starts HTTP servers for new or changed addresses and stops those for
+// addresses that are no longer configured. A server that fails to start is
+// returned as an error, keeping the server it replaces, in which case servers
+// no longer configured are kept too.
+func (l *listeners) apply(servers []*common.HTTPServer) error {
+	configured := map[string]bool{}
+	errs := []error{}
+
+	for _, server := range servers {
+		configured[server.Addr] = true
+
+		existing, ok := l.servers[server.Addr]
+		if ok && reflect.DeepEqual(existing.Config, server) {
+			continue
+		}
+
+		replacement := &tasks.HTTPServer{
 			Name:    "App",
-			Addr:    addr,
-			Handler: r,
-		})
+			Config:  server,
+			Handler: l.handler,
+		}
+
+		if !ok {
+			err := l.svc.Tasks.Start(replacement)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			l.servers[server.Addr] = replacement
+
+			continue
+		}
+
+		// the old server must release the address before the new one
+		// listens on it, so the new one is checked as far as possible
+		// first, such as for its certificates, and the old one is
+		// restored if the new one fails anyway.
+		_, err := server.Server(l.handler)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", replacement.TaskName(), err))
+			continue
+		}
+
+		err = l.svc.Tasks.Replace(existing, replacement)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		l.servers[server.Addr] = replacement
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	for addr, server := range l.servers {
+		if !configured[addr] {
+			l.svc.Tasks.Remove(server)
+			delete(l.servers, addr)
+		}
 	}
 
 	return nil
@@ -0,0 +1,16 @@
+// Code generated by docgen. DO NOT EDIT.
+
+package v1service
+
+import "github.com/svalevka/go/pkg/config"
+
+func init() {
+	config.RegisterDocs("github.com/svalevka/go/svc/systemd-service-ui/v1service", map[string]string{
+		"Config":          "Config contains the configuration used to configure the systemd-service-ui web application and server.",
+		"Config.Admin":    "Admin configures the administrative HTTP server, used to change log levels at runtime.",
+		"Config.Listen":   "Listen configures the HTTP servers accepting connections for the app, each with its own address, TLS and timeouts.",
+		"Config.Services": "Services controls what services the systemd-service-ui is allowed to manage, no other services can be managed without this.",
+		"Service":         "Service configures services that may be managed.",
+		"Service.Pattern": "Pattern is a regular expression matching the names of the services.",
+	})
+}
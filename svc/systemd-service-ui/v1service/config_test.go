@@ -0,0 +1,100 @@
+package v1service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/svalevka/go/pkg/config"
+)
+
+func TestDefaultConfigLoads(t *testing.T) {
+	b, err := config.RenderDefaults(&Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yml")
+
+	err = os.WriteFile(path, b, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = config.FromFile(path, &Config{})
+	if err != nil {
+		t.Fatalf("default configuration doesn't load: %v\n%s", err, b)
+	}
+}
+
+func TestConfigMigrations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+
+	err := os.WriteFile(path, []byte(`
+listen:
+- localhost:8081 # app
+services:
+# web services
+- ^web- # web
+- ^api-
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{}
+
+	err = config.FromFile(path, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cfg.Listen) != 1 || cfg.Listen[0].Addr != "localhost:8081" {
+		t.Errorf("listen = %+v, want the address upgraded to a server", cfg.Listen)
+	}
+
+	if len(cfg.Services) != 2 || cfg.Services[0].Pattern != "^web-" {
+		t.Errorf("services = %+v, want the pattern upgraded to a service", cfg.Services)
+	}
+
+	migrated, err := config.MigrateFiles([]string{path}, &Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if migrated[0].From != 1 || migrated[0].To != 3 {
+		t.Errorf("migrated = %+v, want version 1 to 3", migrated[0])
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `version: 3
+listen:
+  - addr: localhost:8081 # app
+services:
+  # web services
+  - pattern: ^web- # web
+  - pattern: ^api-
+`
+	if string(b) != want {
+		t.Errorf("config.yml = %q, want %q", b, want)
+	}
+}
+
+func TestConfigMigrationsMixed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+
+	// version 1 files can't contain service entries.
+	err := os.WriteFile(path, []byte("services:\n- pattern: ^web-\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = config.FromFile(path, &Config{})
+	if err == nil {
+		t.Error("want an error for a service entry in version 1")
+	}
+}
@@ -0,0 +1,195 @@
+package v1service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/svalevka/go/pkg/config"
+	"github.com/svalevka/go/pkg/config/common"
+	"github.com/svalevka/go/pkg/service"
+	"github.com/svalevka/go/pkg/tasks"
+)
+
+// freeAddr returns a local address that is free to listen on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := ln.Addr().String()
+	ln.Close()
+
+	return addr
+}
+
+func httpServer(t *testing.T, addr string) *common.HTTPServer {
+	t.Helper()
+
+	s := &common.HTTPServer{Addr: addr}
+
+	err := config.SetDefaults(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+// get returns the body served at addr, or an error.
+func get(addr string) (string, error) {
+	resp, err := http.Get("http://" + addr)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+
+	return string(b), err
+}
+
+// runListeners runs listeners serving ok in a Runner until the test ends,
+// returning the error of the Runner once it stops.
+func runListeners(t *testing.T, servers []*common.HTTPServer) (*listeners, chan error) {
+	t.Helper()
+
+	svc := &service.Runner{Tasks: &tasks.Runner{}}
+
+	l := &listeners{
+		svc: svc,
+		handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "ok")
+		}),
+		servers: map[string]*tasks.HTTPServer{},
+	}
+
+	err := l.apply(servers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ready := make(chan struct{})
+	svc.Tasks.TasksReady = func() { close(ready) }
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- svc.Tasks.Run(ctx)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+
+	select {
+	case <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("listeners not ready")
+	}
+
+	return l, stopped
+}
+
+func TestListenersApply(t *testing.T) {
+	a, b := freeAddr(t), freeAddr(t)
+
+	l, _ := runListeners(t, []*common.HTTPServer{httpServer(t, a)})
+
+	err := l.apply([]*common.HTTPServer{httpServer(t, b)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if body, err := get(b); err != nil || body != "ok" {
+		t.Errorf("get %s = %q, %v, want the added server", b, body, err)
+	}
+
+	if _, err := get(a); err == nil {
+		t.Errorf("get %s succeeded, want the removed server stopped", a)
+	}
+
+	changed := httpServer(t, b)
+	changed.WriteTimeout = 2 * time.Minute
+
+	err = l.apply([]*common.HTTPServer{changed})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l.servers[b].Config != changed {
+		t.Error("want the changed server replaced")
+	}
+
+	if body, err := get(b); err != nil || body != "ok" {
+		t.Errorf("get %s = %q, %v, want the replaced server", b, body, err)
+	}
+}
+
+func TestListenersApplyFails(t *testing.T) {
+	a := freeAddr(t)
+
+	l, stopped := runListeners(t, []*common.HTTPServer{httpServer(t, a)})
+
+	// the address is taken by another process.
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+
+	err = l.apply([]*common.HTTPServer{httpServer(t, taken.Addr().String())})
+
+	var taskErr *tasks.TaskError
+	if !errors.As(err, &taskErr) {
+		t.Fatalf("err = %v, want the server failing to listen", err)
+	}
+
+	// the service keeps running, and so does the server no longer
+	// configured, as the configuration wasn't applied.
+	select {
+	case err := <-stopped:
+		t.Fatalf("runner stopped with %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if body, err := get(a); err != nil || body != "ok" {
+		t.Errorf("get %s = %q, %v, want the previous server kept", a, body, err)
+	}
+
+	if _, ok := l.servers[taken.Addr().String()]; ok {
+		t.Error("want the failed server forgotten")
+	}
+}
+
+func TestListenersApplyInvalidReplacement(t *testing.T) {
+	a := freeAddr(t)
+
+	l, _ := runListeners(t, []*common.HTTPServer{httpServer(t, a)})
+	previous := l.servers[a]
+
+	invalid := httpServer(t, a)
+	invalid.TLS = common.ServerTLS{Cert: "missing.pem", Key: "missing.pem"}
+
+	err := l.apply([]*common.HTTPServer{invalid})
+	if err == nil {
+		t.Fatal("want an error for missing certificates")
+	}
+
+	if l.servers[a] != previous {
+		t.Error("want the previous server kept")
+	}
+
+	if body, err := get(a); err != nil || body != "ok" {
+		t.Errorf("get %s = %q, %v, want the previous server serving", a, body, err)
+	}
+}
@@ -3,12 +3,15 @@ package v1service
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/coreos/go-systemd/v22/dbus"
 
+	"github.com/svalevka/go/pkg/log"
 	v1 "github.com/svalevka/go/svc/systemd-service-ui/v1"
 )
 
@@ -27,6 +30,7 @@ type Systemd interface {
 
 // Dbus is a Systemd implementation that is backed directly by Dbus.
 type Dbus struct {
+	mu      sync.RWMutex
 	managed []*regexp.Regexp
 	conn    *dbus.Conn
 }
@@ -40,6 +44,15 @@ func NewDbus(ctx context.Context, managed []*regexp.Regexp) (*Dbus, error) {
 	return &Dbus{conn: conn, managed: managed}, nil
 }
 
+// SetManaged replaces the regular expressions controlling which services are
+// managed, such as when the configuration is reloaded.
+func (d *Dbus) SetManaged(managed []*regexp.Regexp) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.managed = managed
+}
+
 func (d *Dbus) Close() error {
 	d.conn.Close()
 	return nil
@@ -99,6 +112,8 @@ func (d *Dbus) StartService(ctx context.Context, service string) error {
 		return fmt.Errorf("expected status done, got %q", status)
 	}
 
+	log.FromContext(ctx).Info("service started", slog.String("unit", service))
+
 	return nil
 }
 
@@ -118,6 +133,8 @@ func (d *Dbus) RestartService(ctx context.Context, service string) error {
 		return fmt.Errorf("expected status done, got %q", status)
 	}
 
+	log.FromContext(ctx).Info("service restarted", slog.String("unit", service))
+
 	return nil
 }
 
@@ -137,6 +154,8 @@ func (d *Dbus) StopService(ctx context.Context, service string) error {
 		return fmt.Errorf("expected status done, got %q", status)
 	}
 
+	log.FromContext(ctx).Info("service stopped", slog.String("unit", service))
+
 	return nil
 }
 
@@ -145,6 +164,9 @@ func (d *Dbus) isManagedService(v string) bool {
 		return false
 	}
 
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
 	for _, re := range d.managed {
 		if re.MatchString(v) {
 			return true